@@ -0,0 +1,42 @@
+// angle provides the Angle type, so that angles always carry their unit.
+package main
+
+import (
+	"math"
+)
+
+// Angle is an angle that can be given and read in either degrees or radians.
+// Its zero value is an angle of 0.
+type Angle struct {
+	radians float64
+}
+
+// Degrees returns the angle of d degrees.
+func Degrees(d float64) Angle {
+	return Angle{d / 180 * math.Pi}
+}
+
+// Radians returns the angle of r radians.
+func Radians(r float64) Angle {
+	return Angle{r}
+}
+
+// Degrees returns the angle in degrees.
+func (a Angle) Degrees() float64 {
+	return a.radians / math.Pi * 180
+}
+
+// Radians returns the angle in radians.
+func (a Angle) Radians() float64 {
+	return a.radians
+}
+
+// Add returns the sum of two angles.
+func (a Angle) Add(b Angle) Angle {
+	return Angle{a.radians + b.radians}
+}
+
+// Scale returns the angle multiplied by f.
+func (a Angle) Scale(f float64) Angle {
+	return Angle{a.radians * f}
+}
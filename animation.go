@@ -0,0 +1,99 @@
+// animation provides resumable rendering of animations, recording finished
+// frames in a manifest file so that a render restarted after a crash skips
+// them.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// RenderAnimation renders frames 0 through frames-1 with render, clearing the
+// renderer before each one. render is expected to save the frame it draws.
+// Every frame render finishes is appended to the manifest file, which is
+// created if it does not exist, and frames already listed there are skipped,
+// so running the same animation again picks up where it stopped. If the
+// renderer has a Progress function, it is told about each frame rendered. It
+// returns an error if the manifest cannot be read or written or render fails,
+// in which case the frames before the failed one stay recorded.
+func (r *Renderer) RenderAnimation(manifest string, frames int, render func(r *Renderer, frame int) error) error {
+	done, complete, err := readManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(manifest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// A crash can leave half a line at the end, which is finished off so that
+	// the next frame starts a line of its own.
+	if !complete {
+		if _, err := file.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+
+	remaining := 0
+	for frame := 0; frame < frames; frame++ {
+		if !done[frame] {
+			remaining++
+		}
+	}
+	progress := newProgressTracker(remaining, r.Progress)
+
+	for frame := 0; frame < frames; frame++ {
+		if done[frame] {
+			continue
+		}
+
+		r.Clear()
+		if err := render(r, frame); err != nil {
+			return fmt.Errorf("frame %d: %v", frame, err)
+		}
+		if _, err := fmt.Fprintln(file, frame); err != nil {
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			return err
+		}
+		progress.step()
+	}
+	return file.Close()
+}
+
+// readManifest reads the frame numbers listed one to a line in a manifest
+// file. A missing file lists no frames. A last line without a newline was cut
+// off while being written and is ignored; complete reports whether the file
+// has no such line. It returns an error if the file cannot be read or holds a
+// line that is not a frame number.
+func readManifest(manifest string) (done map[int]bool, complete bool, err error) {
+	data, err := os.ReadFile(manifest)
+	if os.IsNotExist(err) {
+		return map[int]bool{}, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	complete = len(data) == 0 || data[len(data)-1] == '\n'
+	lines := bytes.Split(data, []byte("\n"))
+	lines = lines[:len(lines)-1]
+
+	done = make(map[int]bool)
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		frame, err := strconv.Atoi(string(bytes.TrimSpace(line)))
+		if err != nil {
+			return nil, false, fmt.Errorf("manifest %s line %d: %q is not a frame number", manifest, i+1, line)
+		}
+		done[frame] = true
+	}
+	return done, complete, nil
+}
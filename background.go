@@ -0,0 +1,74 @@
+// background provides background images and cube maps drawn behind all of a
+// scene's geometry.
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// CubeMap is an environment made of six square images, one for each face of a
+// cube surrounding the scene, named by the axis direction they face.
+type CubeMap struct {
+	PosX, NegX, PosY, NegY, PosZ, NegZ image.Image
+}
+
+// Sample returns the color of the cube map seen in direction d.
+func (c CubeMap) Sample(d Vector) []int {
+	ax, ay, az := math.Abs(d[0]), math.Abs(d[1]), math.Abs(d[2])
+
+	var face image.Image
+	var u, v float64
+	switch {
+	case ax >= ay && ax >= az && d[0] > 0:
+		face, u, v = c.PosX, -d[2]/ax, -d[1]/ax
+	case ax >= ay && ax >= az:
+		face, u, v = c.NegX, d[2]/ax, -d[1]/ax
+	case ay >= az && d[1] > 0:
+		face, u, v = c.PosY, d[0]/ay, d[2]/ay
+	case ay >= az:
+		face, u, v = c.NegY, d[0]/ay, -d[2]/ay
+	case d[2] > 0:
+		face, u, v = c.PosZ, d[0]/az, -d[1]/az
+	default:
+		face, u, v = c.NegZ, -d[0]/az, -d[1]/az
+	}
+
+	if face == nil {
+		return []int{255, 255, 255}
+	}
+	return sampleImage(face, (u+1)/2, (v+1)/2)
+}
+
+// DrawBackground stretches img over the whole of a screen.
+func DrawBackground(screen [][][]int, img image.Image) {
+	height, width := len(screen), screenWidth(screen)
+	for i := range screen {
+		for j := range screen[i] {
+			copy(screen[i][j], sampleImage(img, (float64(j)+0.5)/float64(width), (float64(i)+0.5)/float64(height)))
+		}
+	}
+}
+
+// DrawEnvironment fills a screen with a cube map as seen through a 90 degree
+// field of view looking down the negative z axis.
+func DrawEnvironment(screen [][][]int, c CubeMap) {
+	height, width := len(screen), screenWidth(screen)
+	for i := range screen {
+		for j := range screen[i] {
+			x := 2*(float64(j)+0.5)/float64(width) - 1
+			y := 1 - 2*(float64(i)+0.5)/float64(height)
+			copy(screen[i][j], c.Sample(Vector{x, y, -1}))
+		}
+	}
+}
+
+// sampleImage returns the color of img at (u, v), where (0, 0) is the top left
+// corner and (1, 1) the bottom right.
+func sampleImage(img image.Image, u, v float64) []int {
+	bounds := img.Bounds()
+	x := bounds.Min.X + clampInt(int(u*float64(bounds.Dx())), 0, bounds.Dx()-1)
+	y := bounds.Min.Y + clampInt(int(v*float64(bounds.Dy())), 0, bounds.Dy()-1)
+	r, g, b, _ := img.At(x, y).RGBA()
+	return []int{int(r >> 8), int(g >> 8), int(b >> 8)}
+}
@@ -0,0 +1,96 @@
+// billboard provides billboards: flat quads that always face the camera, used
+// for labels, particles, and impostors.
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Billboard is a quad of Width by Height world units that always faces the
+// camera. It is drawn with Texture stretched over it, or filled with Color if
+// Texture is nil.
+type Billboard struct {
+	Width, Height float64
+	Color         []int
+	Texture       image.Image
+}
+
+// DrawBillboard draws a billboard centered on position with the renderer,
+// facing its camera. Through a perspective camera, it shrinks with distance
+// like everything else, and it is not drawn if position is behind the eye.
+func (r *Renderer) DrawBillboard(position Vector, b Billboard) {
+	points := NewEdgeMatrix()
+	points.AddPoint(position[0], position[1], position[2])
+	view, err := r.view(points)
+	if err != nil {
+		return
+	}
+	center := columnVector(view, 0)
+	if !finite(center[0], center[1], center[2]) {
+		return
+	}
+
+	scale := r.screenScale(position)
+	halfWidth, halfHeight := b.Width*scale/2, b.Height*scale/2
+	if halfWidth <= 0 || halfHeight <= 0 {
+		return
+	}
+
+	left, right := center[0]-halfWidth, center[0]+halfWidth
+	bottom, top := center[1]-halfHeight, center[1]+halfHeight
+	z := center[2]
+
+	if b.Texture == nil {
+		color := b.Color
+		if color == nil {
+			color = r.Color
+		}
+		r.Target.FillTriangle(left, bottom, z, right, bottom, z, right, top, z, color)
+		r.Target.FillTriangle(left, bottom, z, right, top, z, left, top, z, color)
+		return
+	}
+
+	// Only the part of the sprite on the screen is sampled.
+	bounds := b.Texture.Bounds()
+	lastX, lastY := float64(screenWidth(r.Screen)-1), float64(len(r.Screen)-1)
+	for y := math.Max(math.Ceil(bottom), 0); y <= math.Min(top, lastY); y++ {
+		for x := math.Max(math.Ceil(left), 0); x <= math.Min(right, lastX); x++ {
+			u := (x - left) / (right - left)
+			v := (top - y) / (top - bottom)
+			tx := bounds.Min.X + clampInt(int(u*float64(bounds.Dx())), 0, bounds.Dx()-1)
+			ty := bounds.Min.Y + clampInt(int(v*float64(bounds.Dy())), 0, bounds.Dy()-1)
+			if _, _, _, alpha := b.Texture.At(tx, ty).RGBA(); alpha == 0 {
+				continue
+			}
+			r.Target.Plot(x, y, z, sampleImage(b.Texture, u, v))
+		}
+	}
+}
+
+// screenScale returns how many pixels a unit across the camera's line of sight
+// at position spans on screen, which shrinks with distance through a
+// perspective camera.
+func (r *Renderer) screenScale(position Vector) float64 {
+	c := r.Camera
+	// The line of sight is the direction w grows along, or for parallel
+	// cameras, which leave w alone, the direction z does. Moving across it
+	// leaves w alone, so the move is only divided by w.
+	sight := Vector{c[3][0], c[3][1], c[3][2]}
+	if sight.Length() == 0 {
+		sight = Vector{c[2][0], c[2][1], c[2][2]}
+	}
+	across := sight.Cross(Vector{0, 1, 0})
+	if across.Length() == 0 {
+		across = sight.Cross(Vector{1, 0, 0})
+	}
+	if across.Length() == 0 {
+		across = Vector{1, 0, 0}
+	}
+	across = across.Normalize()
+
+	x := c[0][0]*across[0] + c[0][1]*across[1] + c[0][2]*across[2]
+	y := c[1][0]*across[0] + c[1][1]*across[1] + c[1][2]*across[2]
+	w := c[3][0]*position[0] + c[3][1]*position[1] + c[3][2]*position[2] + c[3][3]
+	return math.Hypot(x, y) / w
+}
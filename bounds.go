@@ -0,0 +1,87 @@
+// bounds provides bounding boxes of edge matrices and helpers for framing
+// geometry on a screen.
+package main
+
+import (
+	"math"
+)
+
+// Box is an axis-aligned bounding box.
+type Box struct {
+	Min, Max [3]float64
+}
+
+// BoundingBox computes the smallest box containing every point of an edge
+// matrix. It returns false if the edge matrix has no points.
+func BoundingBox(edges EdgeMatrix) (Box, bool) {
+	if edges.checkShape() != nil || edges.Len() == 0 {
+		return Box{}, false
+	}
+
+	var b Box
+	for axis := 0; axis < 3; axis++ {
+		b.Min[axis], b.Max[axis] = math.Inf(1), math.Inf(-1)
+		for _, v := range edges[axis] {
+			b.Min[axis] = math.Min(b.Min[axis], v)
+			b.Max[axis] = math.Max(b.Max[axis], v)
+		}
+	}
+	return b, true
+}
+
+// Center returns the point at the center of the box.
+func (b Box) Center() [3]float64 {
+	var c [3]float64
+	for axis := range c {
+		c[axis] = (b.Min[axis] + b.Max[axis]) / 2
+	}
+	return c
+}
+
+// Size returns the width, height, and depth of the box.
+func (b Box) Size() [3]float64 {
+	var s [3]float64
+	for axis := range s {
+		s[axis] = b.Max[axis] - b.Min[axis]
+	}
+	return s
+}
+
+// MakeFrameMatrix creates a matrix that scales and moves the box so that its
+// x and y extents fit a screen of size width by height, centered, leaving a
+// margin of the given fraction of the screen on every side. It returns the
+// matrix.
+func MakeFrameMatrix(b Box, width, height int, margin float64) [][]float64 {
+	size, center := b.Size(), b.Center()
+	usableWidth := float64(width) * (1 - 2*margin)
+	usableHeight := float64(height) * (1 - 2*margin)
+
+	scale := math.Inf(1)
+	if size[0] > 0 {
+		scale = usableWidth / size[0]
+	}
+	if size[1] > 0 {
+		scale = math.Min(scale, usableHeight/size[1])
+	}
+	if math.IsInf(scale, 1) {
+		scale = 1
+	}
+
+	m := MakeTranslationMatrix(-center[0], -center[1], -center[2])
+	dilation := MakeDilationMatrix(scale, scale, scale)
+	MultiplyMatrices(&dilation, &m)
+	translation := MakeTranslationMatrix(float64(width)/2, float64(height)/2, 0)
+	MultiplyMatrices(&translation, &m)
+	return m
+}
+
+// Frame points the renderer's camera at an edge matrix so that the whole of it
+// fits on the screen with a small margin. Empty edge matrices leave the camera
+// unchanged.
+func (r *Renderer) Frame(edges EdgeMatrix) {
+	b, ok := BoundingBox(edges)
+	if !ok {
+		return
+	}
+	r.Camera = MakeFrameMatrix(b, screenWidth(r.Screen), len(r.Screen), 0.05)
+}
@@ -0,0 +1,90 @@
+// camera provides a movable camera, placed by an eye position looking at a
+// target, that builds the renderer's camera matrix.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Camera is a camera at Eye looking toward Target, turned so that Up points up
+// the screen as nearly as it can. FOV is the vertical field of view of a
+// perspective camera; a zero FOV gives a parallel view at the scene's own
+// scale instead.
+type Camera struct {
+	Eye, Target, Up Vector
+	FOV             Angle
+}
+
+// LookAt creates the view matrix of a camera at eye looking toward target with
+// up as the up direction. The matrix moves the eye to the origin and turns the
+// scene so that the camera looks down the negative z axis with up along the
+// positive y axis. It returns an error if eye and target are the same point or
+// up is parallel to the line between them.
+func LookAt(eye, target, up Vector) ([][]float64, error) {
+	forward := target.Sub(eye).Normalize()
+	if forward.Length() == 0 {
+		return nil, fmt.Errorf("look at: eye and target are both %v", eye)
+	}
+	right := forward.Cross(up).Normalize()
+	if right.Length() == 0 {
+		return nil, fmt.Errorf("look at: up %v is parallel to the view direction", up)
+	}
+	up = right.Cross(forward)
+
+	m := NewMatrix()
+	MakeIdentity(m)
+	for i, axis := range []Vector{right, up, forward.Scale(-1)} {
+		m[i][0], m[i][1], m[i][2] = axis[0], axis[1], axis[2]
+		m[i][3] = -axis.Dot(eye)
+	}
+	return m, nil
+}
+
+// Matrix creates the camera matrix that shows what the camera sees on a
+// screen of size width by height, with the target at the center of the
+// screen. Perspective cameras put their field of view across the screen's
+// height. It returns an error if the camera cannot be placed or its FOV is not
+// between 0 and 180 degrees.
+func (c Camera) Matrix(width, height int) ([][]float64, error) {
+	fov := c.FOV.Radians()
+	if !(fov >= 0 && fov < math.Pi) {
+		return nil, fmt.Errorf("camera: field of view %g is not between 0 and 180 degrees", c.FOV.Degrees())
+	}
+
+	m, err := LookAt(c.Eye, c.Target, c.Up)
+	if err != nil {
+		return nil, err
+	}
+
+	if fov > 0 {
+		// Putting the eye the focal length in front of the projection plane
+		// sizes the field of view to the height of the screen.
+		focal := float64(height) / 2 / math.Tan(fov/2)
+		back := MakeTranslate(0, 0, focal)
+		MultiplyMatrices(&back, &m)
+		perspective := MakePerspective(focal)
+		MultiplyMatrices(&perspective, &m)
+	} else {
+		// Parallel views keep the target, rather than the eye, in the plane
+		// z = 0.
+		in := MakeTranslate(0, 0, c.Target.Sub(c.Eye).Length())
+		MultiplyMatrices(&in, &m)
+	}
+
+	center := MakeTranslate(float64(width)/2, float64(height)/2, 0)
+	MultiplyMatrices(&center, &m)
+	return m, nil
+}
+
+// SetCamera points the renderer's camera matrix through c for the size of its
+// screen. It returns an error, leaving the camera unchanged, if c cannot be
+// placed.
+func (r *Renderer) SetCamera(c Camera) error {
+	m, err := c.Matrix(screenWidth(r.Screen), len(r.Screen))
+	if err != nil {
+		return err
+	}
+	r.Camera = m
+	return nil
+}
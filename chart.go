@@ -0,0 +1,100 @@
+// chart provides simple 2D charts, with axes, line series, and scatter plots,
+// drawn straight onto a screen for quick data overlays.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Chart maps data coordinates in a range of x and y onto a region of a
+// screen.
+type Chart struct {
+	Viewport   Viewport
+	XMin, XMax float64
+	YMin, YMax float64
+}
+
+// NewChart creates a chart drawn in viewport showing x from xMin to xMax and y
+// from yMin to yMax. It returns an error if either range is empty or not
+// finite.
+func NewChart(viewport Viewport, xMin, xMax, yMin, yMax float64) (Chart, error) {
+	if !finite(xMin, xMax, yMin, yMax) || !(xMax > xMin) || !(yMax > yMin) {
+		return Chart{}, fmt.Errorf("chart: ranges [%g, %g] and [%g, %g] must be finite and nonempty", xMin, xMax, yMin, yMax)
+	}
+	return Chart{viewport, xMin, xMax, yMin, yMax}, nil
+}
+
+// toScreen converts the data point (x, y) to the point of the screen it is
+// drawn at.
+func (c Chart) toScreen(x, y float64) (float64, float64) {
+	vp := c.Viewport
+	sx := float64(vp.X) + (x-c.XMin)/(c.XMax-c.XMin)*float64(vp.Width)
+	sy := float64(vp.Y) + (y-c.YMin)/(c.YMax-c.YMin)*float64(vp.Height)
+	return sx, sy
+}
+
+// DrawAxes draws the chart's x axis along the bottom of its viewport and its y
+// axis along the left, each with ticks+1 evenly spaced tick marks from the
+// start of its range to the end.
+func (c Chart) DrawAxes(screen [][][]int, color []int, ticks int) {
+	const tickLength = 4
+	x0, y0 := c.toScreen(c.XMin, c.YMin)
+	x1, y1 := c.toScreen(c.XMax, c.YMax)
+	DrawLine(screen, x0, y0, 0, x1, y0, 0, color)
+	DrawLine(screen, x0, y0, 0, x0, y1, 0, color)
+
+	for i := 0; i <= ticks && ticks > 0; i++ {
+		t := float64(i) / float64(ticks)
+		x := x0 + t*(x1-x0)
+		y := y0 + t*(y1-y0)
+		DrawLine(screen, x, y0, 0, x, y0-tickLength, 0, color)
+		DrawLine(screen, x0, y, 0, x0-tickLength, y, 0, color)
+	}
+}
+
+// DrawSeries draws the line joining the data points (xs[i], ys[i]) in order.
+// The options are applied to every line. Points that are not finite break the
+// line. It returns an error if xs and ys differ in length.
+func (c Chart) DrawSeries(screen [][][]int, xs, ys []float64, color []int, opts ...DrawOption) error {
+	if len(xs) != len(ys) {
+		return fmt.Errorf("chart: got %d x values and %d y values", len(xs), len(ys))
+	}
+
+	for i := 1; i < len(xs); i++ {
+		if finite(xs[i-1], ys[i-1], xs[i], ys[i]) {
+			ax, ay := c.toScreen(xs[i-1], ys[i-1])
+			bx, by := c.toScreen(xs[i], ys[i])
+			DrawLine(screen, ax, ay, 0, bx, by, 0, color, opts...)
+		}
+	}
+	return nil
+}
+
+// DrawScatter draws a square size pixels across centered on every data point
+// (xs[i], ys[i]). Points that are not finite are skipped. It returns an error
+// if xs and ys differ in length.
+func (c Chart) DrawScatter(screen [][][]int, xs, ys []float64, color []int, size int) error {
+	if len(xs) != len(ys) {
+		return fmt.Errorf("chart: got %d x values and %d y values", len(xs), len(ys))
+	}
+
+	radius := math.Max(float64(size), 1) / 2
+	for i := range xs {
+		if !finite(xs[i], ys[i]) {
+			continue
+		}
+
+		x, y := c.toScreen(xs[i], ys[i])
+		fillRect(screen, x-radius, y-radius, x+radius, y+radius, color)
+	}
+	return nil
+}
+
+// fillRect fills the pixels of a screen whose centers lie in the rectangle
+// from (left, bottom) to (right, top).
+func fillRect(screen [][][]int, left, bottom, right, top float64, color []int) {
+	for y := math.Ceil(bottom); y < top; y++ {
+		fillSpan(screen, y, left, right, func(float64) []int { return color })
+	}
+}
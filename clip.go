@@ -0,0 +1,126 @@
+// clip provides clipping planes, which cut away the geometry on one side of
+// them, optionally capping the cut, so that the insides of models can be
+// inspected.
+package main
+
+// ClipPlane is a plane through Point that cuts away everything on the side
+// Normal points away from. If Cap is set, the cut is capped with a surface of
+// that color.
+type ClipPlane struct {
+	Point, Normal Vector
+	Cap           []int
+}
+
+// distance returns how far p lies on the kept side of the plane, in units of
+// the length of its normal. Points cut away have negative distances.
+func (c ClipPlane) distance(p Vector) float64 {
+	return p.Sub(c.Point).Dot(c.Normal)
+}
+
+// clipEdges returns the parts of the edges of an edge matrix left by the
+// renderer's clipping planes. Without clipping planes, or if the edge matrix
+// is malformed, it returns the edge matrix itself.
+func (r *Renderer) clipEdges(edges EdgeMatrix) EdgeMatrix {
+	if len(r.ClipPlanes) == 0 || edges.checkShape() != nil {
+		return edges
+	}
+
+	clipped := NewEdgeMatrix()
+	for i := 0; i+1 < edges.Len(); i += 2 {
+		if p0, p1, ok := r.clipEdge(columnVector(edges, i), columnVector(edges, i+1)); ok {
+			clipped.AddPoint(p0[0], p0[1], p0[2])
+			clipped.AddPoint(p1[0], p1[1], p1[2])
+		}
+	}
+	return clipped
+}
+
+// clipEdge returns the part of the edge from p0 to p1 left by the renderer's
+// clipping planes, and whether any of it is left.
+func (r *Renderer) clipEdge(p0, p1 Vector) (Vector, Vector, bool) {
+	for _, c := range r.ClipPlanes {
+		d0, d1 := c.distance(p0), c.distance(p1)
+		if d0 < 0 && d1 < 0 {
+			return p0, p1, false
+		} else if d0 < 0 {
+			p0 = clipPoint(p0, p1, d0, d1)
+		} else if d1 < 0 {
+			p1 = clipPoint(p1, p0, d1, d0)
+		}
+	}
+	return p0, p1, true
+}
+
+// clipPolygons returns the parts of the triangles of a polygon matrix left by
+// the renderer's clipping planes, split into triangles wound the same way.
+// Without clipping planes, or if the polygon matrix is malformed, it returns
+// the polygon matrix itself.
+func (r *Renderer) clipPolygons(polygons PolygonMatrix) PolygonMatrix {
+	if len(r.ClipPlanes) == 0 || EdgeMatrix(polygons).checkShape() != nil {
+		return polygons
+	}
+
+	clipped := NewPolygonMatrix()
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		corners := []Vector{columnVector(polygons, i), columnVector(polygons, i+1), columnVector(polygons, i+2)}
+		for _, c := range r.ClipPlanes {
+			corners = clipPolygon(corners, c)
+		}
+		for j := 1; j+1 < len(corners); j++ {
+			for _, p := range []Vector{corners[0], corners[j], corners[j+1]} {
+				EdgeMatrix(clipped).AddPoint(p[0], p[1], p[2])
+			}
+		}
+	}
+	return clipped
+}
+
+// clipPolygon returns the part of the convex polygon with corners in order
+// left by a clipping plane, by the Sutherland-Hodgman algorithm.
+func clipPolygon(corners []Vector, c ClipPlane) []Vector {
+	var kept []Vector
+	for i, p := range corners {
+		q := corners[(i+1)%len(corners)]
+		dp, dq := c.distance(p), c.distance(q)
+		if dp >= 0 {
+			kept = append(kept, p)
+		}
+		if (dp < 0) != (dq < 0) {
+			kept = append(kept, clipPoint(p, q, dp, dq))
+		}
+	}
+	return kept
+}
+
+// clipPoint returns where the segment from p to q, whose ends lie at
+// distances dp and dq on either side of a clipping plane, crosses the plane.
+func clipPoint(p, q Vector, dp, dq float64) Vector {
+	return p.Add(q.Sub(p).Scale(dp / (dp - dq)))
+}
+
+// clipCap returns the color the renderer caps cuts with, which is the cap of
+// its first clipping plane to have one, or nil if none does.
+func (r *Renderer) clipCap() []int {
+	for _, c := range r.ClipPlanes {
+		if c.Cap != nil {
+			return c.Cap
+		}
+	}
+	return nil
+}
+
+// fillCap fills the triangle whose corners are columns i, i+1, and i+2 of a
+// matrix already seen through the renderer's camera with its cap color if the
+// renderer caps cuts and the triangle faces away. Through a cut in a closed
+// surface, only the back faces of its far side can be seen, so filling them
+// makes the surface look solid. It reports whether it filled the triangle.
+func (r *Renderer) fillCap(view [][]float64, i int) bool {
+	cap := r.clipCap()
+	if cap == nil || !backFacing(view, i) {
+		return false
+	}
+
+	p0, p1, p2 := columnVector(view, i), columnVector(view, i+1), columnVector(view, i+2)
+	r.Target.FillTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], cap)
+	return true
+}
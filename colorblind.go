@@ -0,0 +1,86 @@
+// colorblind provides filters that simulate color vision deficiencies, so that
+// renders can be checked for readability by colorblind viewers.
+package main
+
+import (
+	"math"
+)
+
+// Machado et al. (2009) matrices for full-severity dichromacy, applied in
+// linear RGB.
+var (
+	protanopiaMatrix = [3][3]float64{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}
+	deuteranopiaMatrix = [3][3]float64{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}
+	tritanopiaMatrix = [3][3]float64{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}
+)
+
+// Protanopia returns a filter that simulates how a screen looks without red
+// cones.
+func Protanopia() Filter {
+	return colorMatrixFilter(protanopiaMatrix)
+}
+
+// Deuteranopia returns a filter that simulates how a screen looks without
+// green cones.
+func Deuteranopia() Filter {
+	return colorMatrixFilter(deuteranopiaMatrix)
+}
+
+// Tritanopia returns a filter that simulates how a screen looks without blue
+// cones.
+func Tritanopia() Filter {
+	return colorMatrixFilter(tritanopiaMatrix)
+}
+
+// colorMatrixFilter returns a filter that multiplies every pixel's linear RGB
+// color by m.
+func colorMatrixFilter(m [3][3]float64) Filter {
+	return func(screen [][][]int) [][][]int {
+		out := newScreenLike(screen)
+		for i, row := range screen {
+			for j, rgb := range row {
+				var linear [3]float64
+				for c := range linear {
+					linear[c] = srgbToLinear(rgb[c])
+				}
+				for c := range linear {
+					v := m[c][0]*linear[0] + m[c][1]*linear[1] + m[c][2]*linear[2]
+					out[i][j][c] = linearToSRGB(v)
+				}
+			}
+		}
+		return out
+	}
+}
+
+// srgbToLinear converts an sRGB channel from 0 to 255 to linear light from 0
+// to 1.
+func srgbToLinear(c int) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light from 0 to 1 to an sRGB channel from 0 to
+// 255.
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return clampChannel(v * 12.92 * 255)
+	}
+	return clampChannel((1.055*math.Pow(v, 1/2.4) - 0.055) * 255)
+}
@@ -0,0 +1,73 @@
+// colormap provides colormaps, which turn scalar values into colors, and
+// painting meshes with per-vertex scalar values.
+package main
+
+import "math"
+
+// Colormap is a list of evenly spaced colors running from the color of the
+// lowest value to the color of the highest. Values between two colors are
+// blended linearly.
+type Colormap [][3]int
+
+var (
+	// Viridis is the perceptually uniform colormap from dark purple through
+	// teal to yellow.
+	Viridis = Colormap{
+		{68, 1, 84}, {71, 44, 122}, {59, 81, 139}, {44, 113, 142}, {33, 144, 141},
+		{39, 173, 129}, {92, 200, 99}, {170, 220, 50}, {253, 231, 37},
+	}
+	// Jet is the rainbow colormap from dark blue through cyan and yellow to
+	// dark red.
+	Jet = Colormap{
+		{0, 0, 128}, {0, 0, 255}, {0, 128, 255}, {0, 255, 255}, {128, 255, 128},
+		{255, 255, 0}, {255, 128, 0}, {255, 0, 0}, {128, 0, 0},
+	}
+	// Grayscale is the colormap from black to white.
+	Grayscale = Colormap{{0, 0, 0}, {255, 255, 255}}
+)
+
+// At returns the color of t, from 0 to 1, in the colormap. Values of t outside
+// that range are clamped to it.
+func (c Colormap) At(t float64) []int {
+	if len(c) == 0 {
+		return []int{0, 0, 0}
+	} else if math.IsNaN(t) || t <= 0 || len(c) == 1 {
+		return []int{c[0][0], c[0][1], c[0][2]}
+	} else if t >= 1 {
+		last := c[len(c)-1]
+		return []int{last[0], last[1], last[2]}
+	}
+
+	pos := t * float64(len(c)-1)
+	i := int(pos)
+	f := pos - float64(i)
+	color := make([]int, 3)
+	for j := range color {
+		color[j] = clampChannel(float64(c[i][j]) + f*float64(c[i+1][j]-c[i][j]))
+	}
+	return color
+}
+
+// PaintScalars returns a copy of the mesh whose vertex colors come from
+// mapping values, one for each vertex, through the colormap. The smallest
+// value maps to the start of the colormap and the largest to its end. Vertices
+// past the end of values keep the color of the start of the colormap.
+func (m Mesh) PaintScalars(values []float64, c Colormap) Mesh {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+
+	painted := Mesh{Vertices: m.Vertices, Faces: m.Faces}
+	painted.Colors = make([][]int, len(m.Vertices))
+	for i := range painted.Colors {
+		t := 0.0
+		if i < len(values) && high > low {
+			t = (values[i] - low) / (high - low)
+		}
+		painted.Colors[i] = c.At(t)
+	}
+	return painted
+}
@@ -0,0 +1,88 @@
+// contour provides extraction of contour lines, or isolines, of scalar fields
+// over heightmaps and meshes.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// AddContours adds the contour lines of a heightmap at each of the given
+// levels to the edge matrix. heights[i][j] is the height at
+// (j*cell, i*cell), and every contour line lies at z equal to its level so it
+// can be drawn over the terrain. Each square of the grid is split into two
+// triangles, which avoids the ambiguous cases of marching squares. It returns
+// an error if the rows of heights differ in length or cell is not positive.
+func (m EdgeMatrix) AddContours(heights [][]float64, cell float64, levels ...float64) error {
+	if !(cell > 0) || math.IsInf(cell, 1) {
+		return fmt.Errorf("contours: cell size %g is not a positive number", cell)
+	}
+	for i, row := range heights {
+		if len(row) != len(heights[0]) {
+			return fmt.Errorf("contours: row %d has %d heights, want %d", i, len(row), len(heights[0]))
+		}
+	}
+
+	for i := 0; i+1 < len(heights); i++ {
+		for j := 0; j+1 < len(heights[i]); j++ {
+			x0, y0 := float64(j)*cell, float64(i)*cell
+			x1, y1 := x0+cell, y0+cell
+			a, b := Vector{x0, y0, 0}, Vector{x1, y0, 0}
+			c, d := Vector{x1, y1, 0}, Vector{x0, y1, 0}
+			va, vb, vc, vd := heights[i][j], heights[i][j+1], heights[i+1][j+1], heights[i+1][j]
+
+			for _, level := range levels {
+				m.addContourSegment([3]Vector{a, b, c}, [3]float64{va, vb, vc}, level, true)
+				m.addContourSegment([3]Vector{a, c, d}, [3]float64{va, vc, vd}, level, true)
+			}
+		}
+	}
+	return nil
+}
+
+// Contours returns an edge matrix holding the contour lines of a scalar field
+// over the mesh at each of the given levels. values holds one value for every
+// vertex; faces using a vertex without a value are skipped. The lines lie on
+// the surface of the mesh.
+func (m Mesh) Contours(values []float64, levels ...float64) EdgeMatrix {
+	edges := NewEdgeMatrix()
+	for _, f := range m.Faces {
+		if f[0] >= len(values) || f[1] >= len(values) || f[2] >= len(values) {
+			continue
+		}
+
+		corners := [3]Vector{m.Vertices[f[0]], m.Vertices[f[1]], m.Vertices[f[2]]}
+		v := [3]float64{values[f[0]], values[f[1]], values[f[2]]}
+		for _, level := range levels {
+			edges.addContourSegment(corners, v, level, false)
+		}
+	}
+	return edges
+}
+
+// addContourSegment adds the piece of the contour line at level crossing the
+// triangle with the given corners and values at them, if there is one, to the
+// edge matrix. If flat is set, the piece is placed at z equal to level rather
+// than on the triangle.
+func (m EdgeMatrix) addContourSegment(corners [3]Vector, v [3]float64, level float64, flat bool) {
+	crossings := make([]Vector, 0, 2)
+	for k := 0; k < 3; k++ {
+		p, q := k, (k+1)%3
+		if (v[p] >= level) == (v[q] >= level) {
+			continue
+		}
+
+		t := (level - v[p]) / (v[q] - v[p])
+		crossings = append(crossings, corners[p].Add(corners[q].Sub(corners[p]).Scale(t)))
+	}
+	if len(crossings) != 2 {
+		return
+	}
+
+	for _, c := range crossings {
+		if flat {
+			c[2] = level
+		}
+		m.AddPoint(c[0], c[1], c[2])
+	}
+}
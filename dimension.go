@@ -0,0 +1,86 @@
+// dimension provides dimension lines, which annotate renders with the
+// distances between points in the style of engineering drawings.
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// dimensionArrow is the length in pixels of the arrowheads of dimension lines,
+// and dimensionGap the gap in pixels between a measured point and its
+// extension line, and between the dimension line and its label.
+const (
+	dimensionArrow = 8.0
+	dimensionGap   = 3.0
+)
+
+// DrawDimension draws a dimension line measuring the distance between points a
+// and b, seen through the renderer's camera, onto its screen in its current
+// color, over everything already drawn. Extension lines run from the points
+// offset pixels out to one side, which is to the left looking from a toward b
+// on screen, or to the right for negative offsets. The dimension line runs
+// between them with an arrowhead at each end, and is labeled at its middle
+// with the distance between the points to two decimal places. It returns an
+// error if either point cannot be seen through the camera.
+func (r *Renderer) DrawDimension(a, b Vector, offset float64) error {
+	points := NewEdgeMatrix()
+	points.AddEdge(a[0], a[1], a[2], b[0], b[1], b[2])
+	view, err := r.view(points)
+	if err != nil {
+		return err
+	}
+	pa, pb := columnVector(view, 0), columnVector(view, 1)
+	if !finite(pa[0], pa[1], pb[0], pb[1]) {
+		return fmt.Errorf("dimension: points cannot be seen through the camera")
+	}
+	pa[2], pb[2] = 0, 0
+
+	// dir runs along the dimension line and side out from the measured
+	// points toward it.
+	dir := pb.Sub(pa).Normalize()
+	if !finite(dir[0], dir[1]) {
+		dir = Vector{1, 0, 0}
+	}
+	side := Vector{-dir[1], dir[0], 0}
+	if offset < 0 {
+		side, offset = side.Scale(-1), -offset
+	}
+
+	line := func(p, q Vector) {
+		DrawLine(r.Screen, p[0], p[1], 0, q[0], q[1], 0, r.Color)
+	}
+	ea, eb := pa.Add(side.Scale(offset)), pb.Add(side.Scale(offset))
+	if offset > dimensionGap {
+		line(pa.Add(side.Scale(dimensionGap)), ea.Add(side.Scale(dimensionGap)))
+		line(pb.Add(side.Scale(dimensionGap)), eb.Add(side.Scale(dimensionGap)))
+	}
+	line(ea, eb)
+
+	barb := side.Scale(dimensionArrow / 3)
+	for _, end := range []struct{ tip, back Vector }{
+		{ea, dir.Scale(dimensionArrow)},
+		{eb, dir.Scale(-dimensionArrow)},
+	} {
+		line(end.tip, end.tip.Add(end.back).Add(barb))
+		line(end.tip, end.tip.Add(end.back).Sub(barb))
+	}
+
+	label := formatDimension(b.Sub(a).Length())
+	width, height := TextSize(label, 1)
+	// Move the label out of the way of the line by as far as it reaches
+	// toward the line.
+	reach := (math.Abs(side[0])*float64(width) + math.Abs(side[1])*float64(height)) / 2
+	center := ea.Add(eb).Scale(0.5).Add(side.Scale(dimensionGap + reach))
+	DrawText(r.Screen, math.Round(center[0]-float64(width)/2), math.Round(center[1]-float64(height)/2), label, r.Color)
+	return nil
+}
+
+// formatDimension formats a distance to two decimal places, leaving off
+// trailing zeros.
+func formatDimension(d float64) string {
+	s := strconv.FormatFloat(d, 'f', 2, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -4,21 +4,26 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 const XRES = 500
 const YRES = 500
 const PPMFilename = "pic.ppm"
 
-// NewScreen creates a new screen of size XRES by YRES. It returns the new
-// screen.
-func NewScreen() (screen [][][]int) {
-	screen = make([][][]int, YRES)
+// NewScreen creates a new white screen of size width by height. Drawing
+// routines take their bounds from the screen they draw onto, so screens of any
+// size can be drawn on. It returns the new screen.
+func NewScreen(width, height int) (screen [][][]int) {
+	screen = make([][][]int, height)
 
 	for i, _ := range screen {
-		screen[i] = make([][]int, XRES)
+		screen[i] = make([][]int, width)
 
 		for j, _ := range screen[i] {
 			screen[i][j] = []int{255, 255, 255}
@@ -28,19 +33,55 @@ func NewScreen() (screen [][][]int) {
 	return
 }
 
+// NewZBuffer creates a new z-buffer of size width by height with every depth
+// set to negative infinity. It returns the new z-buffer.
+func NewZBuffer(width, height int) (zbuffer [][]float64) {
+	zbuffer = make([][]float64, height)
+
+	for i := range zbuffer {
+		zbuffer[i] = make([]float64, width)
+	}
+
+	ClearZBuffer(zbuffer)
+	return
+}
+
+// ClearZBuffer sets every depth of a z-buffer to negative infinity.
+func ClearZBuffer(zbuffer [][]float64) {
+	for i := range zbuffer {
+		for j := range zbuffer[i] {
+			zbuffer[i][j] = math.Inf(-1)
+		}
+	}
+}
+
 // DisplayScreen uses XQuartz's "display" command to display a PPM.
 func DisplayScreen(screen [][][]int) {
-	WriteScreenToPPM(screen)
-	_, err := exec.Command("display", PPMFilename).Output()
+	filename := writeTempPPM(screen)
+	defer os.Remove(filename)
+
+	_, err := exec.Command("display", filename).Output()
 	if err != nil {
 		panic(err)
 	}
 }
 
+// CopyScreen returns a copy of a screen that shares none of its colors.
+func CopyScreen(screen [][][]int) [][][]int {
+	out := make([][][]int, len(screen))
+	for i, row := range screen {
+		out[i] = make([][]int, len(row))
+		for j, rgb := range row {
+			out[i][j] = append([]int(nil), rgb...)
+		}
+	}
+	return out
+}
+
 // ClearScreen clears a screen.
 func ClearScreen(screen [][][]int) {
 	for i, _ := range screen {
-		screen[i] = make([][]int, XRES)
+		screen[i] = make([][]int, len(screen[i]))
 
 		for j, _ := range screen[i] {
 			screen[i][j] = []int{255, 255, 255}
@@ -48,32 +89,69 @@ func ClearScreen(screen [][][]int) {
 	}
 }
 
-// WriteScreenToExtension writes a screen to a filename.
+// WriteScreenToExtension writes a screen to a filename. PNG files are encoded
+// directly; other formats are converted from a PPM with ImageMagick.
 func WriteScreenToExtension(screen [][][]int, filename string) {
-	WriteScreenToPPM(screen)
-	_, err := exec.Command("convert", PPMFilename, filename).Output()
+	if strings.EqualFold(filepath.Ext(filename), ".png") {
+		if err := SavePNG(screen, filename); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	ppm := writeTempPPM(screen)
+	defer os.Remove(ppm)
+
+	_, err := exec.Command("convert", ppm, filename).Output()
 	if err != nil {
 		panic(err)
 	}
 }
 
+// SavePNG writes a screen to the file named filename as a PNG, without
+// shelling out to ImageMagick. It returns an error if the file cannot be
+// written.
+func SavePNG(screen [][][]int, filename string) error {
+	return writePNG(screenToImage(screen), filename)
+}
+
 // WriteScreenToPPM takes a screen as an argument and writes it to a PPM file.
 func WriteScreenToPPM(screen [][][]int) {
-	file, err := os.OpenFile(PPMFilename, os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.Create(PPMFilename)
 	if err != nil {
 		panic(err)
 	}
 
 	defer file.Close()
+	writePPM(file, screen)
+}
 
+// writeTempPPM writes a screen to a new temporary PPM file, so that screens
+// written at the same time never share a file. It returns the file's name.
+func writeTempPPM(screen [][][]int) string {
+	file, err := os.CreateTemp("", "screen-*.ppm")
+	if err != nil {
+		panic(err)
+	}
+
+	defer file.Close()
+	writePPM(file, screen)
+	return file.Name()
+}
+
+// writePPM writes a screen to w in the plain PPM format.
+func writePPM(w io.Writer, screen [][][]int) {
 	var buffer bytes.Buffer
-	buffer.WriteString(fmt.Sprintf("P3 %d %d 255\n", XRES, YRES))
-	for i := 0; i < YRES; i++ {
-		for j := 0; j < XRES; j++ {
+	width := screenWidth(screen)
+	buffer.WriteString(fmt.Sprintf("P3 %d %d 255\n", width, len(screen)))
+	for i := range screen {
+		for j := 0; j < width; j++ {
 			rgb := screen[i][j]
 			buffer.WriteString(fmt.Sprintf("%d %d %d ", uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2])))
 		}
 	}
 
-	file.WriteString(buffer.String())
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		panic(err)
+	}
 }
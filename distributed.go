@@ -0,0 +1,302 @@
+// distributed provides a coordinator that farms the frames of an animation out
+// over HTTP to workers on other machines and collects the frames they render.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxFrameSize is the largest frame, in bytes, a coordinator accepts.
+const maxFrameSize = 64 << 20
+
+// FrameJob is a frame handed out to a worker: the number of the frame and the
+// scene document to render it from.
+type FrameJob struct {
+	Frame int    `json:"frame"`
+	Scene []byte `json:"scene"`
+}
+
+// Coordinator hands out the frames of an animation of a scene to workers and
+// saves the PNG frames they send back into a directory. It serves the
+// protocol RunWorker speaks:
+//
+//	GET /job              lease a frame, answered with a FrameJob as JSON,
+//	                      204 if every frame left is leased, or 410 if the
+//	                      animation is done
+//	PUT /frames/{frame}   upload the PNG of a leased frame
+//
+// Finished frames are recorded in a manifest in the directory, as by
+// RenderAnimation, so a restarted coordinator only hands out the rest. A
+// frame whose worker has not sent it back within Lease is handed out again.
+type Coordinator struct {
+	Scene  []byte
+	Frames int
+	Dir    string
+	Lease  time.Duration
+
+	mu       sync.Mutex
+	mux      *http.ServeMux
+	done     map[int]bool
+	leased   map[int]time.Time
+	finished chan struct{}
+}
+
+// NewCoordinator creates a coordinator for frames 0 through frames-1 of an
+// animation of scene, saving them into dir, which is created if it does not
+// exist. Frames are leased for a minute. It returns an error if dir or its
+// manifest cannot be read or written.
+func NewCoordinator(scene []byte, frames int, dir string) (*Coordinator, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Coordinator{
+		Scene:    scene,
+		Frames:   frames,
+		Dir:      dir,
+		Lease:    time.Minute,
+		mux:      http.NewServeMux(),
+		leased:   make(map[int]time.Time),
+		finished: make(chan struct{}),
+	}
+
+	done, complete, err := readManifest(c.manifest())
+	if err != nil {
+		return nil, err
+	} else if !complete {
+		if err := appendManifest(c.manifest(), ""); err != nil {
+			return nil, err
+		}
+	}
+	// Frames a manifest records beyond the animation, left by an earlier
+	// run of a longer one, do not count toward finishing it.
+	for frame := range done {
+		if frame < 0 || frame >= frames {
+			delete(done, frame)
+		}
+	}
+	c.done = done
+	c.checkFinished()
+
+	c.mux.HandleFunc("GET /job", c.serveJob)
+	c.mux.HandleFunc("PUT /frames/{frame}", c.serveFrame)
+	return c, nil
+}
+
+// ServeHTTP answers a worker's request.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.mux.ServeHTTP(w, req)
+}
+
+// Finished returns a channel that is closed once every frame has been saved.
+func (c *Coordinator) Finished() <-chan struct{} {
+	return c.finished
+}
+
+// FramePath returns the name of the file frame is saved to.
+func (c *Coordinator) FramePath(frame int) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("frame-%04d.png", frame))
+}
+
+// manifest returns the name of the coordinator's manifest file.
+func (c *Coordinator) manifest() string {
+	return filepath.Join(c.Dir, "manifest")
+}
+
+// serveJob leases the first frame that is neither done nor leased to the
+// worker asking for one.
+func (c *Coordinator) serveJob(w http.ResponseWriter, req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.done) >= c.Frames {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	now := time.Now()
+	for frame := 0; frame < c.Frames; frame++ {
+		if c.done[frame] {
+			continue
+		} else if leased, ok := c.leased[frame]; ok && now.Sub(leased) < c.Lease {
+			continue
+		}
+
+		c.leased[frame] = now
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FrameJob{frame, c.Scene})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveFrame saves the PNG a worker uploads for a frame and records the frame
+// as done.
+func (c *Coordinator) serveFrame(w http.ResponseWriter, req *http.Request) {
+	frame, err := strconv.Atoi(req.PathValue("frame"))
+	if err != nil || frame < 0 || frame >= c.Frames {
+		http.Error(w, fmt.Sprintf("no frame %q", req.PathValue("frame")), http.StatusNotFound)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxFrameSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+		http.Error(w, fmt.Sprintf("frame %d is not a PNG: %v", frame, err), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done[frame] {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := os.WriteFile(c.FramePath(frame), data, 0644); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := appendManifest(c.manifest(), strconv.Itoa(frame)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c.done[frame] = true
+	delete(c.leased, frame)
+	logf(LevelInfo, "collected frame", Field{"frame", frame}, Field{"done", len(c.done)})
+	c.checkFinished()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkFinished closes the finished channel once every frame is done. The
+// caller must hold the lock unless the coordinator is not yet shared.
+func (c *Coordinator) checkFinished() {
+	if len(c.done) < c.Frames {
+		return
+	}
+
+	select {
+	case <-c.finished:
+	default:
+		close(c.finished)
+	}
+}
+
+// appendManifest appends a line to a manifest file and syncs it to disk, so
+// that the line survives a crash.
+func appendManifest(manifest, line string) error {
+	file, err := os.OpenFile(manifest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// RunWorker renders frames for the coordinator at url until the animation is
+// done or ctx is. For each frame it leases, it clears a new renderer, calls
+// render with the job's scene and frame number, and uploads the renderer's
+// filtered screen as a PNG. While every frame left is leased to other
+// workers, it polls every poll. It returns an error if render fails or the
+// coordinator cannot be reached, and ctx's error if ctx is done first.
+func RunWorker(ctx context.Context, url string, poll time.Duration, render func(r *Renderer, scene []byte, frame int) error) error {
+	for {
+		job, ok, err := leaseFrame(ctx, url)
+		if err != nil {
+			return err
+		} else if !ok {
+			return nil
+		} else if job == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(poll):
+			}
+			continue
+		}
+
+		r := NewRenderer()
+		r.Clear()
+		if err := render(r, job.Scene, job.Frame); err != nil {
+			return fmt.Errorf("frame %d: %v", job.Frame, err)
+		}
+
+		encoded, err := r.PNG()
+		if err != nil {
+			return err
+		}
+		if err := uploadFrame(ctx, url, job.Frame, encoded); err != nil {
+			return fmt.Errorf("frame %d: %v", job.Frame, err)
+		}
+	}
+}
+
+// leaseFrame asks the coordinator at url for a frame. It returns the job, or
+// a nil job if none is free yet, and false once the animation is done.
+func leaseFrame(ctx context.Context, url string) (*FrameJob, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/job", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var job FrameJob
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			return nil, false, fmt.Errorf("bad job from %s: %v", url, err)
+		}
+		return &job, true, nil
+	case http.StatusNoContent:
+		return nil, true, nil
+	case http.StatusGone:
+		return nil, false, nil
+	}
+	return nil, false, fmt.Errorf("coordinator %s: %s", url, resp.Status)
+}
+
+// uploadFrame sends the PNG of a frame to the coordinator at url.
+func uploadFrame(ctx context.Context, url string, frame int, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/frames/%d", url, frame), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "image/png")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("coordinator %s: %s: %s", url, resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
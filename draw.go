@@ -3,116 +3,145 @@
 package main
 
 import (
+	"fmt"
 	"math"
 )
 
-var DefaultDrawColor []int = []int{0, 0, 0}
-
-// DrawLines draws an edge matrix onto a screen.
-func DrawLines(edges [][]float64, screen [][][]int) {
-	for i := 0; i < len(edges[0])-1; i += 2 {
-		point := ExtractColumn(edges, i)
-		nextPoint := ExtractColumn(edges, i+1)
-		x0, y0 := point[0], point[1]
-		x1, y1 := nextPoint[0], nextPoint[1]
-		DrawLine(screen, x0, y0, x1, y1)
-	}
+// DrawLines draws an edge matrix onto a screen with the given color. The
+// options are applied to every line. It returns an error if the edge matrix is
+// malformed.
+func DrawLines(edges EdgeMatrix, screen [][][]int, color []int, opts ...DrawOption) error {
+	return RasterizeLines(edges, ScreenRasterizer{Screen: screen}, color, opts...)
 }
 
-// AddPoint adds a point to an edge matrix.
-func AddPoint(m [][]float64, x, y, z float64) {
-	m[0] = append(m[0], x)
-	m[1] = append(m[1], y)
-	m[2] = append(m[2], z)
-	m[3] = append(m[3], 1)
+// FillPolygons fills every triangle of a polygon matrix onto a screen with the
+// given color, one scanline at a time. If zbuffer is not nil, only the parts
+// of triangles nearer than what was already drawn are filled. It returns an
+// error if the polygon matrix is malformed.
+func FillPolygons(polygons PolygonMatrix, screen [][][]int, zbuffer [][]float64, color []int) error {
+	return RasterizeTriangles(polygons, ScreenRasterizer{screen, zbuffer}, color)
 }
 
-// AddEdge adds an edge (two points) to an edge matrix.
-func AddEdge(m [][]float64, params ...float64) {
-	x0, y0, z0 := params[0], params[1], params[2]
-	x1, y1, z1 := params[3], params[4], params[5]
-	AddPoint(m, x0, y0, z0)
-	AddPoint(m, x1, y1, z1)
-}
+// CurveType is the kind of cubic curve generated by AddCurve.
+type CurveType int
 
-// AddCircle adds a circle of center (cx, cy, cz) and radius r to an edge
-// matrix.
-func AddCircle(m [][]float64, params ...float64) {
-	cx, cy, _, r := params[0], params[1], params[2], params[3]
-	for t := 0.0; t <= 1.0; t += 0.001 {
-		x := r*math.Cos(2*math.Pi*t) + cx
-		y := r*math.Sin(2*math.Pi*t) + cy
-		AddPoint(m, x, y, 0)
-	}
-}
+const (
+	// Hermite curves are defined by two endpoints and their tangents.
+	Hermite CurveType = iota
+	// Bezier curves are defined by two endpoints and two control points.
+	Bezier
+)
 
 // AddCurve adds the curve bounded by the 4 points passed as parameters
-// to an edge matrix.
-func AddCurve(m [][]float64, x0, y0, x1, y1, x2, y2, x3, y3, step float64, curveType string) {
-	xCoefs := generateCurveCoefs(x0, x1, x2, x3, curveType)
-	yCoefs := generateCurveCoefs(y0, y1, y2, y3, curveType)
+// to the edge matrix. Curves with a non-positive step are skipped. It returns
+// an error if curveType is unknown.
+func (m EdgeMatrix) AddCurve(x0, y0, x1, y1, x2, y2, x3, y3, step float64, curveType CurveType) error {
+	if step <= 0 {
+		logf(LevelWarn, "skipping curve with non-positive step", Field{"step", step})
+		return nil
+	}
+
+	xCoefs, err := generateCurveCoefs(x0, x1, x2, x3, curveType)
+	if err != nil {
+		return err
+	}
+	yCoefs, err := generateCurveCoefs(y0, y1, y2, y3, curveType)
+	if err != nil {
+		return err
+	}
 
 	for t := 0.0; t <= 1.0; t += step {
 		x := CubicEval(t, xCoefs)
 		y := CubicEval(t, yCoefs)
 
-		AddPoint(m, x, y, 0)
+		m.AddPoint(x, y, 0)
 	}
+	return nil
 }
 
-func generateCurveCoefs(p0, p1, p2, p3 float64, curveType string) [][]float64 {
+func generateCurveCoefs(p0, p1, p2, p3 float64, curveType CurveType) ([][]float64, error) {
 	m := make([][]float64, 4)
 	var coefGenerator [][]float64
-	if curveType == "hermite" {
+	switch curveType {
+	case Hermite:
 		coefGenerator = MakeHermite()
-	} else if curveType == "bezier" {
+	case Bezier:
 		coefGenerator = MakeBezier()
+	default:
+		return nil, fmt.Errorf("unknown curve type %d", curveType)
 	}
 	m[0] = []float64{p0}
 	m[1] = []float64{p1}
 	m[2] = []float64{p2}
 	m[3] = []float64{p3}
-	MultiplyMatrices(&coefGenerator, &m)
-	return m
+	err := MultiplyMatrices(&coefGenerator, &m)
+	return m, err
 }
 
 // AddBox adds the points for a rectagular prism whose upper-left corner is
-// (x, y, z) with width, height and depth dimensions.
-func AddBox(m [][]float64, a ...float64) {
-	x, y, z, width, height, depth := a[0], a[1], a[2], a[3], a[4], a[5]
-	AddEdge(m, x, y, z, x+width, y, z)
-	AddEdge(m, x, y, z, x, y-height, z)
-	AddEdge(m, x, y, z, x, y, z-depth)
-
-	AddEdge(m, x, y-height, z, x, y-height, z-depth)
-	AddEdge(m, x, y-height, z, x+width, y-height, z)
-	AddEdge(m, x, y-height, z-depth, x+width, y-height, z-depth)
-	AddEdge(m, x+width, y-height, z, x+width, y-height, z-depth)
-
-	AddEdge(m, x, y, z-depth, x+width, y, z-depth)
-	AddEdge(m, x, y, z-depth, x, y-height, z-depth)
+// (x, y, z) with width, height and depth dimensions. It returns an error if a
+// does not hold 6 finite numbers.
+func (m EdgeMatrix) AddBox(a ...float64) error {
+	if err := checkParams("box", a, 6); err != nil {
+		return err
+	}
 
-	AddEdge(m, x+width, y, z, x+width, y-height, z)
-	AddEdge(m, x+width, y, z, x+width, y, z-depth)
-	AddEdge(m, x+width, y, z-depth, x+width, y-height, z-depth)
+	x, y, z, width, height, depth := a[0], a[1], a[2], a[3], a[4], a[5]
+	m.AddEdge(x, y, z, x+width, y, z)
+	m.AddEdge(x, y, z, x, y-height, z)
+	m.AddEdge(x, y, z, x, y, z-depth)
+
+	m.AddEdge(x, y-height, z, x, y-height, z-depth)
+	m.AddEdge(x, y-height, z, x+width, y-height, z)
+	m.AddEdge(x, y-height, z-depth, x+width, y-height, z-depth)
+	m.AddEdge(x+width, y-height, z, x+width, y-height, z-depth)
+
+	m.AddEdge(x, y, z-depth, x+width, y, z-depth)
+	m.AddEdge(x, y, z-depth, x, y-height, z-depth)
+
+	m.AddEdge(x+width, y, z, x+width, y-height, z)
+	m.AddEdge(x+width, y, z, x+width, y, z-depth)
+	m.AddEdge(x+width, y, z-depth, x+width, y-height, z-depth)
+	return nil
 }
 
 // AddSphere adds all the points for a sphere with center (cx, cy, cz) and
-// radius r.
-func AddSphere(m [][]float64, a ...float64) {
+// radius r, given as a[0] through a[3]. An optional a[4] sets the step, from 0
+// to 1, between samples of the sphere's parameters; it defaults to 0.01. It
+// returns an error if a does not hold 4 finite numbers, r is negative, or the
+// step is out of range.
+func (m EdgeMatrix) AddSphere(a ...float64) error {
+	if err := checkParams("sphere", a, 4); err != nil {
+		return err
+	}
+
 	cx, cy, cz, r := a[0], a[1], a[2], a[3]
-	for _, p := range GenerateSphere(cx, cy, cz, r) {
-		AddEdge(m, p[0], p[1], p[2], p[0]+1, p[1]+1, p[2]+1)
+	if r < 0 {
+		return fmt.Errorf("sphere: radius %g is negative", r)
+	}
+
+	step := 0.01
+	if len(a) >= 5 {
+		step = a[4]
+	}
+	if !(step > 0 && step <= 1) {
+		return fmt.Errorf("sphere: step %g is not in (0, 1]", step)
 	}
+
+	for _, p := range GenerateSphere(cx, cy, cz, r, step) {
+		m.AddEdge(p[0], p[1], p[2], p[0]+1, p[1]+1, p[2]+1)
+	}
+	return nil
 }
 
 // GenerateSphere generates all the points along the surface of a sphere with
-// center (cx, cy, cz) and radius r. It returns a matrix of the points.
-func GenerateSphere(cx, cy, cz, r float64) [][]float64 {
+// center (cx, cy, cz) and radius r, sampling its parameters every step. It
+// returns a matrix of the points.
+func GenerateSphere(cx, cy, cz, r, step float64) [][]float64 {
 	points := make([][]float64, 0)
-	for i := 0.0; i <= 1.0; i += 0.01 {
+	for i := 0.0; i <= 1.0; i += step {
 		fi := 2 * math.Pi * i
-		for j := 0.0; j <= 0.5; j += 0.01 {
+		for j := 0.0; j <= 0.5; j += step {
 			theta := 2 * math.Pi * j
 			x := r*math.Cos(theta) + cx
 			y := r*math.Sin(theta)*math.Cos(fi) + cy
@@ -124,12 +153,22 @@ func GenerateSphere(cx, cy, cz, r float64) [][]float64 {
 }
 
 // AddTorus adds all the points required to make a torus with center
-// (cx, cy, cz) and radii r1 and r2.
-func AddTorus(m [][]float64, a ...float64) {
+// (cx, cy, cz) and radii r1 and r2. It returns an error if a does not hold 5
+// finite numbers or a radius is negative.
+func (m EdgeMatrix) AddTorus(a ...float64) error {
+	if err := checkParams("torus", a, 5); err != nil {
+		return err
+	}
+
 	cx, cy, cz, r1, r2 := a[0], a[1], a[2], a[3], a[4]
+	if r1 < 0 || r2 < 0 {
+		return fmt.Errorf("torus: radii %g and %g must not be negative", r1, r2)
+	}
+
 	for _, p := range GenerateTorus(cx, cy, cz, r1, r2) {
-		AddEdge(m, p[0], p[1], p[2], p[0]+1, p[1]+1, p[2]+1)
+		m.AddEdge(p[0], p[1], p[2], p[0]+1, p[1]+1, p[2]+1)
 	}
+	return nil
 }
 
 // GenerateTorus  generates all the points along the surface of a torus with
@@ -149,6 +188,102 @@ func GenerateTorus(cx, cy, cz, r2, r1 float64) [][]float64 {
 	return points
 }
 
+// AddCylinder adds the edges of a capped cylinder to the edge matrix. Its base
+// is centered on (cx, cy, cz) with radius r, and it rises h along the y axis.
+// The params are (cx, cy, cz, r, h, step), where step, from 0 to 1, is the
+// fraction of a turn between the edges around the cylinder. It returns an error
+// if params does not hold 6 finite numbers, r is negative, or step is out of
+// range.
+func (m EdgeMatrix) AddCylinder(params ...float64) error {
+	cx, cy, cz, r, h, step, err := roundSolidParams("cylinder", params)
+	if err != nil {
+		return err
+	}
+
+	for t := 0.0; t < 1.0; t += step {
+		next := math.Min(t+step, 1)
+		x0, z0 := r*math.Cos(2*math.Pi*t)+cx, r*math.Sin(2*math.Pi*t)+cz
+		x1, z1 := r*math.Cos(2*math.Pi*next)+cx, r*math.Sin(2*math.Pi*next)+cz
+
+		m.AddEdge(x0, cy, z0, x1, cy, z1)
+		m.AddEdge(x0, cy+h, z0, x1, cy+h, z1)
+		m.AddEdge(x0, cy, z0, x0, cy+h, z0)
+		m.AddEdge(cx, cy, cz, x0, cy, z0)
+		m.AddEdge(cx, cy+h, cz, x0, cy+h, z0)
+	}
+	return nil
+}
+
+// AddCone adds the edges of a capped cone to the edge matrix. Its base is
+// centered on (cx, cy, cz) with radius r, and its tip is h above the base along
+// the y axis. The params are (cx, cy, cz, r, h, step), where step, from 0 to 1,
+// is the fraction of a turn between the edges around the cone. It returns an
+// error if params does not hold 6 finite numbers, r is negative, or step is out
+// of range.
+func (m EdgeMatrix) AddCone(params ...float64) error {
+	cx, cy, cz, r, h, step, err := roundSolidParams("cone", params)
+	if err != nil {
+		return err
+	}
+
+	for t := 0.0; t < 1.0; t += step {
+		next := math.Min(t+step, 1)
+		x0, z0 := r*math.Cos(2*math.Pi*t)+cx, r*math.Sin(2*math.Pi*t)+cz
+		x1, z1 := r*math.Cos(2*math.Pi*next)+cx, r*math.Sin(2*math.Pi*next)+cz
+
+		m.AddEdge(x0, cy, z0, x1, cy, z1)
+		m.AddEdge(x0, cy, z0, cx, cy+h, cz)
+		m.AddEdge(cx, cy, cz, x0, cy, z0)
+	}
+	return nil
+}
+
+// roundSolidParams checks and unpacks the (cx, cy, cz, r, h, step) params of a
+// cylinder or cone called name.
+func roundSolidParams(name string, params []float64) (cx, cy, cz, r, h, step float64, err error) {
+	if err = checkParams(name, params, 6); err != nil {
+		return
+	}
+
+	cx, cy, cz, r, h, step = params[0], params[1], params[2], params[3], params[4], params[5]
+	if r < 0 {
+		err = fmt.Errorf("%s: radius %g is negative", name, r)
+	} else if !(step > 0 && step <= 1) {
+		err = fmt.Errorf("%s: step %g is not in (0, 1]", name, step)
+	}
+	return
+}
+
+// clipLine clips the line from (x0, y0) to (x1, y1) to the rectangle from
+// (minX, minY) to (maxX, maxY) with the Liang-Barsky algorithm. It returns the
+// ends of the part of the line inside the rectangle, and whether there is any.
+func clipLine(x0, y0, x1, y1, minX, minY, maxX, maxY float64) (float64, float64, float64, float64, bool) {
+	dx, dy := x1-x0, y1-y0
+	enter, leave := 0.0, 1.0
+	// Each edge of the rectangle is checked as p*t <= q, where t runs from
+	// 0 at the start of the line to 1 at its end.
+	for _, edge := range [4][2]float64{{-dx, x0 - minX}, {dx, maxX - x0}, {-dy, y0 - minY}, {dy, maxY - y0}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+
+		t := q / p
+		if p < 0 {
+			enter = math.Max(enter, t)
+		} else {
+			leave = math.Min(leave, t)
+		}
+	}
+	if enter > leave {
+		return 0, 0, 0, 0, false
+	}
+	return x0 + enter*dx, y0 + enter*dy, x0 + leave*dx, y0 + leave*dy, true
+}
+
 // CubicEval evaluates a cubic function with variable x and coefficients.
 func CubicEval(x float64, coefs [][]float64) (y float64) {
 	for i := 3.0; i >= 0.0; i-- {
@@ -157,8 +292,50 @@ func CubicEval(x float64, coefs [][]float64) (y float64) {
 	return
 }
 
-// DrawLine draws a line from (x0, y0) to (x1, y1) onto a screen.
-func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
+// DrawLine draws a line from (x0, y0, z0) to (x1, y1, z1) onto a screen with
+// the given color. Options may override the color and set the width, sampling
+// step, sub-pixel sampling, antialiasing, and z-buffer of the line. The z
+// values are only used for depth testing against the z-buffer. Lines that
+// leave the screen are clipped to it first, so only pixels near the screen are
+// visited.
+func DrawLine(screen [][][]int, x0, y0, z0, x1, y1, z1 float64, color []int, opts ...DrawOption) {
+	if !finite(x0, y0, z0, x1, y1, z1) {
+		logf(LevelWarn, "skipping line with non-finite endpoints",
+			Field{"x0", x0}, Field{"y0", y0}, Field{"x1", x1}, Field{"y1", y1})
+		return
+	}
+
+	o := newDrawOptions(color, opts...)
+	o.from, o.to = [3]float64{x0, y0, z0}, [3]float64{x1, y1, z1}
+
+	_, _, in0 := screenIndex(screen, x0, y0)
+	_, _, in1 := screenIndex(screen, x1, y1)
+	if !in0 || !in1 {
+		logf(LevelDebug, "clipping line that leaves the screen",
+			Field{"x0", x0}, Field{"y0", y0}, Field{"x1", x1}, Field{"y1", y1})
+
+		// Clip to a margin around the screen wide enough for the line's
+		// brush and antialiasing, so the edges of the screen are drawn as
+		// before. Depths and gradients still run along the whole line.
+		margin := float64(o.width)/2 + 2
+		var ok bool
+		x0, y0, x1, y1, ok = clipLine(x0, y0, x1, y1, -margin, -margin,
+			float64(screenWidth(screen)-1)+margin, float64(len(screen)-1)+margin)
+		if !ok {
+			return
+		}
+	}
+	if o.aa {
+		drawLineAA(screen, x0, y0, x1, y1, o)
+		return
+	} else if o.subpixel > 1 {
+		drawLineSubpixel(screen, x0, y0, x1, y1, o)
+		return
+	} else if o.step > 0 {
+		drawLineSampled(screen, x0, y0, x1, y1, o)
+		return
+	}
+
 	if x1 < x0 {
 		x0, x1 = x1, x0
 		y0, y1 = y1, y0
@@ -176,7 +353,7 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 
 		y = y0
 		for y <= y1 {
-			plot(screen, x, y)
+			o.plot(screen, x, y)
 			y++
 		}
 
@@ -189,7 +366,7 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 	if slope >= 0 && slope <= 1 { // octant 1
 		d = 2*A + B
 		for x <= x1 && y <= y1 {
-			plot(screen, x, y)
+			o.plot(screen, x, y)
 			if d > 0 {
 				y++
 				d += 2 * B
@@ -202,7 +379,7 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 	if slope > 1 { // octant 2
 		d = A + 2*B
 		for x <= x1 && y <= y1 {
-			plot(screen, x, y)
+			o.plot(screen, x, y)
 			if d < 0 {
 				x++
 				d += 2 * A
@@ -215,7 +392,7 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 	if slope < 0 && slope >= -1 { // octant 8
 		d = 2*A - B
 		for x <= x1 && y >= y1 {
-			plot(screen, x, y)
+			o.plot(screen, x, y)
 			if d < 0 {
 				y--
 				d -= 2 * B
@@ -228,7 +405,7 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 	if slope < -1 { // octant 7
 		d = A - 2*B
 		for x <= x1 && y >= y1 {
-			plot(screen, x, y)
+			o.plot(screen, x, y)
 			if d > 0 {
 				x++
 				d += 2 * A
@@ -239,36 +416,86 @@ func DrawLine(screen [][][]int, x0, y0, x1, y1 float64) {
 	}
 }
 
-// SetColor sets the color to draw with.
-func SetColor(color string) {
-	if color == "yellow" {
-		DefaultDrawColor = []int{255, 255, 0}
-	} else if color == "white" {
-		DefaultDrawColor = []int{255, 255, 255}
-	} else if color == "black" {
-		DefaultDrawColor = make([]int, 3)
+// DrawLineGradient draws a line from (x0, y0) to (x1, y1) onto a screen whose
+// color blends from c0 at its start to c1 at its end, pixel by pixel along
+// the line. Options apply as they do to DrawLine.
+func DrawLineGradient(screen [][][]int, x0, y0 float64, c0 []int, x1, y1 float64, c1 []int, opts ...DrawOption) {
+	DrawLine(screen, x0, y0, 0, x1, y1, 0, c0, append(opts, WithGradient(c0, c1))...)
+}
+
+// plot draws a point (x, y) onto a screen with the given color. The color is
+// copied into the pixel, so changing it later leaves the screen untouched.
+// Points outside the bounds of the screen are ignored.
+func plot(screen [][][]int, x, y float64, color []int) {
+	if col, row, ok := screenIndex(screen, x, y); ok {
+		copy(screen[row][col], color)
 	}
 }
 
-// plot draws a point (x, y) onto a screen with the default draw color.
-func plot(screen [][][]int, x, y float64) {
-	newX, newY := float64ToInt(x), YRES-float64ToInt(y)-1
-	if newX >= 0 && newX < XRES && newY >= 0 && newY < YRES {
-		screen[newY][newX] = DefaultDrawColor[:]
+// testDepth reports whether depth z is in front of the depth zbuffer holds for
+// the pixel of a screen at (x, y), recording z if so. Ties pass, so that later
+// drawing wins among shapes at the same depth. Points outside the screen
+// fail.
+func testDepth(screen [][][]int, zbuffer [][]float64, x, y, z float64) bool {
+	col, row, ok := screenIndex(screen, x, y)
+	if !ok {
+		return false
+	} else if row >= len(zbuffer) || col >= len(zbuffer[row]) {
+		return true
+	} else if z < zbuffer[row][col] {
+		return false
 	}
+	zbuffer[row][col] = z
+	return true
 }
 
-// DrawLineFromParams gets arguments from a params slice.
-func DrawLineFromParams(screen [][][]int, params ...float64) {
-	if len(params) >= 4 {
-		DrawLine(screen, params[0], params[1], params[2], params[3])
+// blend mixes color into the pixel at (x, y) of a screen, weighted by alpha in
+// [0, 1]. Points outside the bounds of the screen are ignored.
+func blend(screen [][][]int, x, y float64, color []int, alpha float64) {
+	col, row, ok := screenIndex(screen, x, y)
+	if !ok || len(color) < 3 {
+		return
 	}
+
+	old := screen[row][col]
+	mixed := make([]int, 3)
+	for i := range mixed {
+		mixed[i] = int(math.Round(float64(old[i])*(1-alpha) + float64(color[i])*alpha))
+	}
+	screen[row][col] = mixed
 }
 
-// float64ToInt rounds a float64 without truncating it. It returns an int.
-func float64ToInt(f float64) int {
-	if f-float64(int(f)) < 0.5 {
-		return int(f)
+// screenIndex converts the point (x, y) to the column and row of a screen it
+// falls in. It returns false if the point is outside the screen.
+func screenIndex(screen [][][]int, x, y float64) (col, row int, ok bool) {
+	height := len(screen)
+	if height == 0 {
+		return 0, 0, false
+	}
+	width := len(screen[0])
+
+	col, row = int(math.Round(x)), height-int(math.Round(y))-1
+	ok = col >= 0 && col < width && row >= 0 && row < height
+	return
+}
+
+// DrawLineFromParams gets arguments from a params slice. It returns an error if
+// params does not hold 4 finite numbers (x0, y0, x1, y1).
+func DrawLineFromParams(screen [][][]int, color []int, params ...float64) error {
+	if err := checkParams("line", params, 4); err != nil {
+		return err
+	}
+
+	DrawLine(screen, params[0], params[1], 0, params[2], params[3], 0, color)
+	return nil
+}
+
+// finite reports whether every value is neither infinite nor NaN.
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
 	}
-	return int(f + 1)
+	return true
 }
@@ -0,0 +1,128 @@
+// drawtest provides helpers for checking rendered screens against golden
+// images, so that changes to the drawing code can be verified pixel by pixel.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// RenderToScreen creates a blank in-memory screen, passes it to draw, and
+// returns it once draw is done.
+func RenderToScreen(draw func(screen [][][]int)) [][][]int {
+	screen := NewScreen(XRES, YRES)
+	draw(screen)
+	return screen
+}
+
+// HashScreen returns a hex-encoded SHA-256 hash of the colors of a screen.
+func HashScreen(screen [][][]int) string {
+	h := sha256.New()
+	for _, row := range screen {
+		for _, rgb := range row {
+			h.Write([]byte{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2])})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CompareGolden compares a screen against the golden PNG named filename,
+// allowing each color channel to differ by up to tolerance. If the golden
+// image does not exist, the screen is written as the new golden image. If any
+// pixel differs by more than tolerance, an image marking the differing pixels
+// in red is written next to the golden image with a ".diff.png" suffix. It
+// returns the number of differing pixels.
+func CompareGolden(screen [][][]int, filename string, tolerance int) (int, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return 0, writePNG(screenToImage(screen), filename)
+	} else if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	golden, err := png.Decode(file)
+	if err != nil {
+		return 0, err
+	}
+
+	bounds := golden.Bounds()
+	if bounds.Dy() != len(screen) || len(screen) == 0 || bounds.Dx() != len(screen[0]) {
+		return 0, fmt.Errorf("%s is %dx%d, screen is %dx%d", filename,
+			bounds.Dx(), bounds.Dy(), screenWidth(screen), len(screen))
+	}
+
+	diff := image.NewRGBA(bounds)
+	count := 0
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, _ := golden.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			want := []int{int(r >> 8), int(g >> 8), int(b >> 8)}
+			got := screen[y][x]
+
+			if colorsWithin(got, want, tolerance) {
+				diff.Set(x, y, color.RGBA{uint8(got[0]) / 4, uint8(got[1]) / 4, uint8(got[2]) / 4, 255})
+			} else {
+				diff.Set(x, y, color.RGBA{255, 0, 0, 255})
+				count++
+			}
+		}
+	}
+
+	if count > 0 {
+		if err := writePNG(diff, filename+".diff.png"); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// colorsWithin reports whether every channel of a and b differs by at most
+// tolerance.
+func colorsWithin(a, b []int, tolerance int) bool {
+	for i := 0; i < 3; i++ {
+		d := a[i] - b[i]
+		if d > tolerance || -d > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// screenWidth returns the width of a screen, or 0 if it has no rows.
+func screenWidth(screen [][][]int) int {
+	if len(screen) == 0 {
+		return 0
+	}
+	return len(screen[0])
+}
+
+// screenToImage converts a screen to an RGBA image of the same size.
+func screenToImage(screen [][][]int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, screenWidth(screen), len(screen)))
+	for y, row := range screen {
+		for x, rgb := range row {
+			img.Set(x, y, color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 255})
+		}
+	}
+	return img
+}
+
+// writePNG encodes img as a PNG into the file named filename.
+func writePNG(img image.Image, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// drawScene draws the lines and filled triangles the golden tests check.
+func drawScene(screen [][][]int) {
+	DrawLine(screen, 20, 20, 0, 480, 300, 0, []int{255, 0, 0})
+	DrawLine(screen, 40, 460, 0, 300, 40, 0, []int{0, 0, 255}, WithWidth(3))
+	DrawLine(screen, 60, 250, 0, 440, 260, 0, []int{0, 128, 0}, WithAA(true))
+	DrawLineGradient(screen, 100, 400, []int{255, 0, 0}, 400, 420, []int{0, 0, 255}, WithSubpixel(4))
+	s := ScreenRasterizer{Screen: screen}
+	s.FillTriangle(250, 100, 0, 450, 150, 0, 350, 300, 0, []int{200, 200, 0})
+	s.ShadeTriangle(50, 50, 0, 200, 80, 0, 100, 220, 0, []int{255, 0, 0}, []int{0, 255, 0}, []int{0, 0, 255})
+}
+
+func TestRenderToScreen(t *testing.T) {
+	called := false
+	screen := RenderToScreen(func(screen [][][]int) {
+		called = true
+		plot(screen, 0, 0, []int{1, 2, 3})
+	})
+
+	if !called {
+		t.Fatal("draw was not called")
+	}
+	if len(screen) != YRES || screenWidth(screen) != XRES {
+		t.Fatalf("screen is %dx%d, want %dx%d", screenWidth(screen), len(screen), XRES, YRES)
+	}
+	if got := screen[YRES-1][0]; got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("bottom left pixel is %v, want [1 2 3]", got)
+	}
+}
+
+func TestHashScreen(t *testing.T) {
+	a, b := NewScreen(4, 3), NewScreen(4, 3)
+	if HashScreen(a) != HashScreen(b) {
+		t.Error("equal screens hash differently")
+	}
+
+	plot(b, 1, 1, []int{0, 0, 0})
+	if HashScreen(a) == HashScreen(b) {
+		t.Error("different screens hash the same")
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.png")
+	screen := RenderToScreen(drawScene)
+
+	// A missing golden image is written from the screen.
+	if n, err := CompareGolden(screen, golden, 0); err != nil || n != 0 {
+		t.Fatalf("first comparison = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := os.Stat(golden); err != nil {
+		t.Fatalf("golden image was not written: %v", err)
+	}
+
+	if n, err := CompareGolden(screen, golden, 0); err != nil || n != 0 {
+		t.Errorf("comparison with itself = %d, %v; want 0, nil", n, err)
+	}
+
+	changed := RenderToScreen(drawScene)
+	changed[0][0] = []int{changed[0][0][0] - 3, changed[0][0][1], changed[0][0][2]}
+	changed[1][1] = []int{0, 0, 0}
+	if n, err := CompareGolden(changed, golden, 3); err != nil || n != 1 {
+		t.Errorf("comparison with 2 changed pixels at tolerance 3 = %d, %v; want 1, nil", n, err)
+	}
+	if _, err := os.Stat(golden + ".diff.png"); err != nil {
+		t.Errorf("diff image was not written: %v", err)
+	}
+
+	if _, err := CompareGolden(NewScreen(10, 10), golden, 0); err == nil {
+		t.Error("comparison with a screen of another size succeeded")
+	}
+}
+
+func TestGoldenImages(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(screen [][][]int)
+	}{
+		{"scene", drawScene},
+		{"clipped", func(screen [][][]int) {
+			DrawLine(screen, -1e12, -5e11+100, 0, 1e12, 5e11+100, 0, []int{255, 0, 0})
+			DrawLine(screen, 250, -1e15, 0, 260, 1e15, 0, []int{0, 0, 255}, WithSubpixel(4))
+			s := ScreenRasterizer{Screen: screen}
+			s.FillTriangle(-1e9, -1e9, 0, 1e9, -1e9, 0, 250, 300, 0, []int{0, 160, 0})
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			screen := RenderToScreen(test.draw)
+			n, err := CompareGolden(screen, filepath.Join("testdata", test.name+".png"), 0)
+			if err != nil {
+				t.Fatal(err)
+			} else if n > 0 {
+				t.Errorf("%d pixels differ from testdata/%s.png; see testdata/%s.png.diff.png", n, test.name, test.name)
+			}
+		})
+	}
+}
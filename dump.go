@@ -0,0 +1,64 @@
+// dump provides exports of edge and polygon matrices as CSV and plain text
+// tables for inspecting geometry by hand.
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// dumpHeader labels the columns written by WriteCSV and WriteTable.
+var dumpHeader = []string{"column", "shape", "vertex", "x", "y", "z", "w"}
+
+// WriteCSV writes every column of a matrix as a CSV row labeled with its column
+// index, the index of the shape it belongs to, and its vertex within that
+// shape. Shapes are groups of perShape consecutive columns: 2 for edge
+// matrices and 3 for polygon matrices. It returns any error from writing.
+func WriteCSV(w io.Writer, matrix [][]float64, perShape int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(dumpHeader); err != nil {
+		return err
+	}
+
+	for _, row := range dumpRows(matrix, perShape) {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTable writes the same rows as WriteCSV as an aligned plain text table.
+// It returns any error from writing.
+func WriteTable(w io.Writer, matrix [][]float64, perShape int) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	for _, row := range append([][]string{dumpHeader}, dumpRows(matrix, perShape)...) {
+		for _, cell := range row {
+			fmt.Fprintf(tw, "%s\t", cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// dumpRows formats every column of a matrix as a row of dumpHeader's fields.
+func dumpRows(matrix [][]float64, perShape int) [][]string {
+	if perShape < 1 {
+		perShape = 1
+	}
+
+	rows := make([][]string, 0, columnCount(matrix))
+	for i := 0; i < columnCount(matrix); i++ {
+		row := []string{strconv.Itoa(i), strconv.Itoa(i / perShape), strconv.Itoa(i % perShape)}
+		for _, value := range ExtractColumn(matrix, i) {
+			row = append(row, strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,78 @@
+// easing provides easing functions, which shape how an animated value moves
+// between two values over time, so that animations can speed up, slow down,
+// and bounce instead of all moving linearly.
+package main
+
+import "math"
+
+// Easing maps how far through a change time has gone, from 0 to 1, to how far
+// through the change the value has gone. Every easing maps 0 to 0 and 1 to 1.
+type Easing func(t float64) float64
+
+// Linear moves at a steady speed.
+func Linear(t float64) float64 {
+	return t
+}
+
+// EaseIn starts slowly and speeds up.
+func EaseIn(t float64) float64 {
+	return t * t
+}
+
+// EaseOut starts quickly and slows down.
+func EaseOut(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+// EaseInOut starts slowly, speeds up, and slows down again.
+func EaseInOut(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+// Cubic eases in and out like EaseInOut, but more sharply, spending longer
+// near either end.
+func Cubic(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - 4*math.Pow(1-t, 3)
+}
+
+// Bounce reaches the end early and bounces back off it three times, lower each
+// time, like a ball dropped onto the floor.
+func Bounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + 0.984375
+	}
+}
+
+// easings holds the easings scripts can name.
+var easings = map[string]Easing{
+	"linear":      Linear,
+	"ease-in":     EaseIn,
+	"ease-out":    EaseOut,
+	"ease-in-out": EaseInOut,
+	"cubic":       Cubic,
+	"bounce":      Bounce,
+}
+
+// LookupEasing returns the easing named name: one of linear, ease-in,
+// ease-out, ease-in-out, cubic, or bounce. It reports whether there is one.
+func LookupEasing(name string) (Easing, bool) {
+	e, ok := easings[name]
+	return e, ok
+}
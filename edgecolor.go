@@ -0,0 +1,111 @@
+// edgecolor provides edge matrices whose edges carry their own colors, so that
+// multicolored wireframes can be drawn in a single pass.
+package main
+
+import "fmt"
+
+// ColoredEdges is an edge matrix with a color for each of its edges. An edge
+// with a nil color is drawn in the color it is drawn with.
+type ColoredEdges struct {
+	Edges  EdgeMatrix
+	Colors [][]int
+}
+
+// NewColoredEdges creates colored edges with no edges. It returns them.
+func NewColoredEdges() ColoredEdges {
+	return ColoredEdges{Edges: NewEdgeMatrix()}
+}
+
+// AddEdge adds an edge from (x0, y0, z0) to (x1, y1, z1) of the given color.
+// It returns an error if params does not hold 6 finite numbers.
+func (c *ColoredEdges) AddEdge(color []int, params ...float64) error {
+	if err := c.Edges.AddEdge(params...); err != nil {
+		return err
+	}
+	c.Colors = append(c.Colors, color)
+	return nil
+}
+
+// Add adds every edge of an edge matrix in the given color. It returns an
+// error if the edge matrix is malformed.
+func (c *ColoredEdges) Add(edges EdgeMatrix, color []int) error {
+	if err := edges.checkShape(); err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < edges.Len(); i += 2 {
+		p0, p1 := columnVector(edges, i), columnVector(edges, i+1)
+		c.Edges.AddPoint(p0[0], p0[1], p0[2])
+		c.Edges.AddPoint(p1[0], p1[1], p1[2])
+		c.Colors = append(c.Colors, color)
+	}
+	return nil
+}
+
+// Validate returns an error if the edge matrix is malformed or does not have
+// one color for each edge.
+func (c ColoredEdges) Validate() error {
+	if err := c.Edges.Validate(); err != nil {
+		return err
+	} else if len(c.Colors) != c.Edges.Len()/2 {
+		return fmt.Errorf("colored edges have %d colors for %d edges", len(c.Colors), c.Edges.Len()/2)
+	}
+	return nil
+}
+
+// color returns the color edge i is drawn in when the edges are drawn in
+// color.
+func (c ColoredEdges) color(i int, color []int) []int {
+	if c.Colors[i] != nil {
+		return c.Colors[i]
+	}
+	return color
+}
+
+// RasterizeColoredLines draws every edge onto a rasterizer in its own color,
+// or in color for edges without one. The options are applied to every line.
+// It returns an error if the edges are malformed.
+func RasterizeColoredLines(c ColoredEdges, rasterizer Rasterizer, color []int, opts ...DrawOption) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < c.Edges.Len(); i += 2 {
+		p0, p1 := c.Edges.Column(i), c.Edges.Column(i+1)
+		rasterizer.DrawLine(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], c.color(i/2, color), opts...)
+	}
+	return nil
+}
+
+// DrawColoredLines draws every edge onto a screen in its own color, or in
+// color for edges without one. It returns an error if the edges are
+// malformed.
+func DrawColoredLines(c ColoredEdges, screen [][][]int, color []int, opts ...DrawOption) error {
+	return RasterizeColoredLines(c, ScreenRasterizer{Screen: screen}, color, opts...)
+}
+
+// DrawColoredLines draws every edge onto the renderer's target in its own
+// color, or in the renderer's current color for edges without one, clipped by
+// its clipping planes and seen through its camera. It returns an error if the
+// edges are malformed.
+func (r *Renderer) DrawColoredLines(c ColoredEdges, opts ...DrawOption) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	clipped := NewColoredEdges()
+	for i := 0; i+1 < c.Edges.Len(); i += 2 {
+		if p0, p1, ok := r.clipEdge(columnVector(c.Edges, i), columnVector(c.Edges, i+1)); ok {
+			clipped.Edges.AddPoint(p0[0], p0[1], p0[2])
+			clipped.Edges.AddPoint(p1[0], p1[1], p1[2])
+			clipped.Colors = append(clipped.Colors, c.Colors[i/2])
+		}
+	}
+
+	view, err := r.view(clipped.Edges)
+	if err != nil {
+		return err
+	}
+	clipped.Edges = view
+	return RasterizeColoredLines(clipped, r.Target, r.Color, r.lineOptions(opts)...)
+}
@@ -0,0 +1,176 @@
+// edges provides the EdgeMatrix type and functions for building and
+// inspecting edge matrices.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// EdgeMatrix is a 4xN matrix whose columns are homogeneous points (x, y, z, 1).
+// Every two consecutive columns form an edge.
+type EdgeMatrix [][]float64
+
+// NewEdgeMatrix creates an empty edge matrix. It returns the new edge matrix.
+func NewEdgeMatrix() EdgeMatrix {
+	return make(EdgeMatrix, 4)
+}
+
+// Len returns the number of points in the edge matrix.
+func (m EdgeMatrix) Len() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+// Column returns the point stored in column i of the edge matrix.
+func (m EdgeMatrix) Column(i int) []float64 {
+	return ExtractColumn(m, i)
+}
+
+// Clone returns a deep copy of the edge matrix, which can be transformed
+// without changing the original.
+func (m EdgeMatrix) Clone() EdgeMatrix {
+	return EdgeMatrix(DeepCopy(m))
+}
+
+// Validate checks that the edge matrix has 4 rows of equal length and an even
+// number of points. It returns an error describing the first problem found.
+func (m EdgeMatrix) Validate() error {
+	if err := m.checkShape(); err != nil {
+		return err
+	}
+	if len(m[0])%2 != 0 {
+		return fmt.Errorf("edge matrix has %d points, want an even number", len(m[0]))
+	}
+	return nil
+}
+
+// checkShape checks that the edge matrix has 4 rows of equal length. It
+// returns an error describing the first problem found.
+func (m EdgeMatrix) checkShape() error {
+	if len(m) != 4 {
+		return fmt.Errorf("edge matrix has %d rows, want 4", len(m))
+	}
+	for i, row := range m {
+		if len(row) != len(m[0]) {
+			return fmt.Errorf("edge matrix row %d has %d columns, want %d", i, len(row), len(m[0]))
+		}
+	}
+	return nil
+}
+
+// AddPoint adds a point to the edge matrix.
+func (m EdgeMatrix) AddPoint(x, y, z float64) {
+	m[0] = append(m[0], x)
+	m[1] = append(m[1], y)
+	m[2] = append(m[2], z)
+	m[3] = append(m[3], 1)
+}
+
+// AddEdge adds an edge (two points) to the edge matrix. It returns an error if
+// params does not hold 6 finite numbers (x0, y0, z0, x1, y1, z1).
+func (m EdgeMatrix) AddEdge(params ...float64) error {
+	if err := checkParams("edge", params, 6); err != nil {
+		return err
+	}
+
+	x0, y0, z0 := params[0], params[1], params[2]
+	x1, y1, z1 := params[3], params[4], params[5]
+	m.AddPoint(x0, y0, z0)
+	m.AddPoint(x1, y1, z1)
+	return nil
+}
+
+// AddCircle adds a circle of center (cx, cy, cz) and radius r to the edge
+// matrix. It returns an error if params does not hold 4 finite numbers or r is
+// negative.
+func (m EdgeMatrix) AddCircle(params ...float64) error {
+	if err := checkParams("circle", params, 4); err != nil {
+		return err
+	}
+
+	cx, cy, _, r := params[0], params[1], params[2], params[3]
+	if r < 0 {
+		return fmt.Errorf("circle: radius %g is negative", r)
+	}
+
+	for t := 0.0; t <= 1.0; t += 0.001 {
+		x := r*math.Cos(2*math.Pi*t) + cx
+		y := r*math.Sin(2*math.Pi*t) + cy
+		m.AddPoint(x, y, 0)
+	}
+	return nil
+}
+
+// AddArc adds the arc of the circle of center (cx, cy, cz) and radius r that
+// runs counterclockwise from the angle start to the angle end to the edge
+// matrix. It returns an error if the center or radius is not finite or the
+// radius is negative.
+func (m EdgeMatrix) AddArc(cx, cy, cz, r float64, start, end Angle) error {
+	if err := checkParams("arc", []float64{cx, cy, cz, r}, 4); err != nil {
+		return err
+	} else if r < 0 {
+		return fmt.Errorf("arc: radius %g is negative", r)
+	}
+
+	from, to := start.Radians(), end.Radians()
+	for t := 0.0; t <= 1.0; t += 0.001 {
+		theta := from + t*(to-from)
+		m.AddPoint(r*math.Cos(theta)+cx, r*math.Sin(theta)+cy, cz)
+	}
+	return nil
+}
+
+// checkParams checks that params holds at least n finite numbers for the
+// primitive called name. It returns an error describing the problem otherwise.
+func checkParams(name string, params []float64, n int) error {
+	if len(params) < n {
+		return fmt.Errorf("%s: got %d parameters, want %d", name, len(params), n)
+	}
+	for i, p := range params[:n] {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			return fmt.Errorf("%s: parameter %d is %g, want a finite number", name, i, p)
+		}
+	}
+	return nil
+}
+
+// Append adds every point of src to the end of the edge matrix. It returns an
+// error if either matrix is malformed.
+func (m EdgeMatrix) Append(src EdgeMatrix) error {
+	if err := m.checkShape(); err != nil {
+		return err
+	} else if err := src.checkShape(); err != nil {
+		return err
+	}
+
+	for i := range m {
+		m[i] = append(m[i], src[i]...)
+	}
+	return nil
+}
+
+// AppendTransformed adds every point of src, transformed by t, to the end of
+// the edge matrix. src itself is left unchanged. It returns an error if either
+// matrix is malformed.
+func (m EdgeMatrix) AppendTransformed(src EdgeMatrix, t Transform) error {
+	transformed, err := t.Apply(src)
+	if err != nil {
+		return err
+	}
+	return m.Append(transformed)
+}
+
+// Merge combines edge matrices into a new edge matrix holding all of their
+// points in order. It returns an error if any of them is malformed.
+func Merge(sources ...EdgeMatrix) (EdgeMatrix, error) {
+	merged := NewEdgeMatrix()
+	for _, src := range sources {
+		if err := merged.Append(src); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
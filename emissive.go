@@ -0,0 +1,93 @@
+// emissive provides emissive surfaces, which give off light of their own
+// whatever lights the scene, and a bloom pass that makes them glow, for
+// lamps, neon, and glowing interface elements.
+package main
+
+// glow holds the light given off by the emissive surfaces drawn onto a
+// renderer, with the depth of each, so that glow covered up by surfaces drawn
+// later can be left out.
+type glow struct {
+	screen [][][]int
+	depth  [][]float64
+}
+
+// drawGlow records the light a triangle already seen through the renderer's
+// camera gives off, if the renderer draws emissive surfaces.
+func (r *Renderer) drawGlow(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64) {
+	if r.Emissive == nil {
+		return
+	}
+
+	width, height := screenWidth(r.Screen), len(r.Screen)
+	if r.glow == nil || len(r.glow.screen) != height || screenWidth(r.glow.screen) != width {
+		r.glow = &glow{newScreenLike(r.Screen), NewZBuffer(width, height)}
+	}
+	ScreenRasterizer{r.glow.screen, r.glow.depth}.FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2, r.Emissive)
+}
+
+// emitting returns a color with the light the renderer's surfaces give off
+// added to it.
+func (r *Renderer) emitting(color []int) []int {
+	if r.Emissive == nil {
+		return color
+	}
+	lit := make([]int, len(color))
+	for i := range lit {
+		lit[i] = color[i]
+		if i < len(r.Emissive) {
+			lit[i] = clampChannel(float64(color[i] + r.Emissive[i]))
+		}
+	}
+	return lit
+}
+
+// clearGlow clears the light recorded by drawGlow.
+func (r *Renderer) clearGlow() {
+	if r.glow == nil {
+		return
+	}
+	for _, row := range r.glow.screen {
+		for _, rgb := range row {
+			rgb[0], rgb[1], rgb[2] = 0, 0, 0
+		}
+	}
+	ClearZBuffer(r.glow.depth)
+}
+
+// Bloom returns a pass that makes the emissive surfaces of the renderer it is
+// applied to glow by blurring the light they give off over radius pixels and
+// adding it to the screen, scaled by strength. Only the light of emissive
+// surfaces still in view is blurred.
+func Bloom(radius int, strength float64) Pass {
+	blur := Blur(radius)
+	return func(r *Renderer) Filter {
+		return func(screen [][][]int) [][][]int {
+			out := CopyScreen(screen)
+			if r.glow == nil || len(r.glow.screen) != len(screen) {
+				return out
+			}
+
+			visible := newScreenLike(r.glow.screen)
+			for i, row := range r.glow.screen {
+				for j, rgb := range row {
+					if i < len(r.ZBuffer) && j < len(r.ZBuffer[i]) && r.glow.depth[i][j] >= r.ZBuffer[i][j] {
+						copy(visible[i][j], rgb)
+					}
+				}
+			}
+
+			light := blur(visible)
+			for i, row := range out {
+				for j, rgb := range row {
+					if j >= len(light[i]) {
+						continue
+					}
+					for c := 0; c < 3 && c < len(rgb); c++ {
+						rgb[c] = clampChannel(float64(rgb[c]) + strength*float64(light[i][j][c]))
+					}
+				}
+			}
+			return out
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// encode provides in-memory encoding of screens as PNG and JPEG images, for
+// serving rendered images without touching the filesystem.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// EncodeScreen writes a screen to w as an image of the given format, "png" or
+// "jpeg" (or "jpg"), with JPEGs at quality, from 1 to 100. It returns an
+// error if the format is unknown or the image cannot be written.
+func EncodeScreen(w io.Writer, screen [][][]int, format string, quality int) error {
+	img := screenToImage(screen)
+	switch strings.ToLower(format) {
+	case "png":
+		return png.Encode(w, img)
+	case "jpeg", "jpg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+	return fmt.Errorf("encode: unknown image format %q", format)
+}
+
+// Image returns the renderer's filtered screen as an image.
+func (r *Renderer) Image() image.Image {
+	return screenToImage(r.Output())
+}
+
+// PNG returns the renderer's filtered screen encoded as a PNG. It returns
+// an error if the image cannot be encoded.
+func (r *Renderer) PNG() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := EncodeScreen(&buffer, r.Output(), "png", 0); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// JPEG returns the renderer's filtered screen encoded as a JPEG of quality,
+// from 1 to 100. It returns an error if the image cannot be encoded.
+func (r *Renderer) JPEG(quality int) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := EncodeScreen(&buffer, r.Output(), "jpeg", quality); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
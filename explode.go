@@ -0,0 +1,64 @@
+// explode provides exploded views of assemblies in a scene graph, with their
+// parts pushed out from the center so that how they fit together can be shown
+// in documentation.
+package main
+
+// Exploded returns a copy of the node whose children are moved straight out
+// from the center of the assembly they make up, each by factor times the
+// distance from the assembly's center to its own. Centers are those of the
+// bounding boxes of the children's geometry, in the node's frame. A factor of
+// 0 leaves the children in place. Children without geometry are not moved.
+// The copy shares its geometry and grandchildren with the node, so nested
+// assemblies can be exploded in turn by exploding their nodes first.
+func (n *Node) Exploded(factor float64) *Node {
+	exploded := *n
+	exploded.Children = make([]*Node, len(n.Children))
+
+	points := make([]EdgeMatrix, len(n.Children))
+	all := NewEdgeMatrix()
+	for i, child := range n.Children {
+		points[i] = child.points()
+		for row := range all {
+			all[row] = append(all[row], points[i][row]...)
+		}
+	}
+	box, ok := BoundingBox(all)
+	center := Vector(box.Center())
+
+	for i, child := range n.Children {
+		moved := *child
+		if part, has := BoundingBox(points[i]); ok && has {
+			d := Vector(part.Center()).Sub(center).Scale(factor)
+			moved.Transform = child.Transform.Translate(d[0], d[1], d[2])
+		}
+		exploded.Children[i] = &moved
+	}
+	return &exploded
+}
+
+// points returns every point of the geometry of the visible nodes of the
+// tree rooted at the node, in the frame of the node's parent.
+func (n *Node) points() EdgeMatrix {
+	points := NewEdgeMatrix()
+	n.walk(IdentityTransform(), func(node *Node, world Transform) error {
+		geometry := []EdgeMatrix{node.Edges, EdgeMatrix(node.Polygons)}
+		if node.LOD != nil && len(node.LOD.Levels) > 0 {
+			geometry = append(geometry, node.LOD.Levels[0].Triangles())
+		}
+		for _, m := range geometry {
+			if m.Len() == 0 {
+				continue
+			}
+			placed, err := world.Apply(m)
+			if err != nil {
+				// Malformed geometry fails when the node is drawn.
+				continue
+			}
+			for row := range points {
+				points[row] = append(points[row], placed[row]...)
+			}
+		}
+		return nil
+	})
+	return points
+}
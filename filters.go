@@ -0,0 +1,136 @@
+// filters provides post-process filters, such as blurring and edge detection,
+// that are applied to a screen before it is saved or displayed.
+package main
+
+import (
+	"math"
+)
+
+// Filter computes a new screen from a screen, leaving the original unchanged.
+type Filter func(screen [][][]int) [][][]int
+
+// ApplyFilters runs a screen through each filter in order. It returns the
+// final screen.
+func ApplyFilters(screen [][][]int, filters ...Filter) [][][]int {
+	for _, f := range filters {
+		screen = f(screen)
+	}
+	return screen
+}
+
+// Convolve convolves every color channel of a screen with a square kernel of
+// odd size. Pixels beyond the edges of the screen are treated as copies of the
+// nearest edge pixel. It returns the new screen.
+func Convolve(screen [][][]int, kernel [][]float64) [][][]int {
+	height, width := len(screen), screenWidth(screen)
+	out := newScreenLike(screen)
+	half := len(kernel) / 2
+
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			var sum [3]float64
+			for ki, row := range kernel {
+				for kj, weight := range row {
+					y := clampInt(i+ki-half, 0, height-1)
+					x := clampInt(j+kj-half, 0, width-1)
+					for c := range sum {
+						sum[c] += weight * float64(screen[y][x][c])
+					}
+				}
+			}
+			for c := range sum {
+				out[i][j][c] = clampChannel(sum[c])
+			}
+		}
+	}
+	return out
+}
+
+// Blur returns a filter that blurs a screen with a box kernel reaching radius
+// pixels in every direction.
+func Blur(radius int) Filter {
+	size := 2*radius + 1
+	kernel := NewMatrix(size, size)
+	for i := range kernel {
+		for j := range kernel[i] {
+			kernel[i][j] = 1 / float64(size*size)
+		}
+	}
+	return func(screen [][][]int) [][][]int {
+		return Convolve(screen, kernel)
+	}
+}
+
+// Sharpen returns a filter that sharpens a screen.
+func Sharpen() Filter {
+	kernel := [][]float64{
+		{0, -1, 0},
+		{-1, 5, -1},
+		{0, -1, 0},
+	}
+	return func(screen [][][]int) [][][]int {
+		return Convolve(screen, kernel)
+	}
+}
+
+// EdgeDetect returns a filter that replaces a screen with the magnitude of its
+// brightness gradient, computed with the Sobel operator, so that edges are
+// bright and flat areas are black.
+func EdgeDetect() Filter {
+	gx := [][]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}}
+	gy := [][]float64{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}}
+
+	return func(screen [][][]int) [][][]int {
+		height, width := len(screen), screenWidth(screen)
+		out := newScreenLike(screen)
+
+		for i := 0; i < height; i++ {
+			for j := 0; j < width; j++ {
+				var x, y float64
+				for ki := 0; ki < 3; ki++ {
+					for kj := 0; kj < 3; kj++ {
+						l := float64(luminance(screen[clampInt(i+ki-1, 0, height-1)][clampInt(j+kj-1, 0, width-1)]))
+						x += gx[ki][kj] * l
+						y += gy[ki][kj] * l
+					}
+				}
+				v := clampChannel(math.Hypot(x, y))
+				out[i][j] = []int{v, v, v}
+			}
+		}
+		return out
+	}
+}
+
+// luminance returns the perceived brightness of a color from 0 to 255.
+func luminance(rgb []int) int {
+	return int(math.Round(0.2126*float64(rgb[0]) + 0.7152*float64(rgb[1]) + 0.0722*float64(rgb[2])))
+}
+
+// newScreenLike creates a black screen of the same size as screen. It returns
+// the new screen.
+func newScreenLike(screen [][][]int) [][][]int {
+	out := make([][][]int, len(screen))
+	for i := range out {
+		out[i] = make([][]int, screenWidth(screen))
+		for j := range out[i] {
+			out[i][j] = make([]int, 3)
+		}
+	}
+	return out
+}
+
+// clampChannel rounds v to the nearest color channel value from 0 to 255.
+func clampChannel(v float64) int {
+	return clampInt(int(math.Round(v)), 0, 255)
+}
+
+// clampInt limits v to the range [min, max].
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	} else if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+// geometry provides rays and their intersections with spheres, triangles, and
+// boxes.
+package main
+
+import (
+	"math"
+)
+
+// intersectEpsilon is the smallest distance along a ray counted as a hit, so
+// that rays leaving a surface don't hit it again.
+const intersectEpsilon = 1e-9
+
+// Ray is a half-line starting at Origin and going in Direction.
+type Ray struct {
+	Origin, Direction Vector
+}
+
+// At returns the point at distance t along the ray, measured in multiples of
+// its direction.
+func (r Ray) At(t float64) Vector {
+	return r.Origin.Add(r.Direction.Scale(t))
+}
+
+// IntersectSphere finds where a ray first hits the sphere with center c and
+// radius radius. It returns the distance t along the ray and false if the ray
+// misses.
+func IntersectSphere(ray Ray, c Vector, radius float64) (float64, bool) {
+	oc := ray.Origin.Sub(c)
+	a := ray.Direction.Dot(ray.Direction)
+	b := 2 * oc.Dot(ray.Direction)
+	k := oc.Dot(oc) - radius*radius
+
+	discriminant := b*b - 4*a*k
+	if a == 0 || discriminant < 0 {
+		return 0, false
+	}
+
+	sqrt := math.Sqrt(discriminant)
+	for _, t := range []float64{(-b - sqrt) / (2 * a), (-b + sqrt) / (2 * a)} {
+		if t > intersectEpsilon {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// IntersectTriangle finds where a ray hits the triangle with corners a, b, and
+// c using the Möller–Trumbore algorithm. It returns the distance t along the
+// ray and false if the ray misses.
+func IntersectTriangle(ray Ray, a, b, c Vector) (float64, bool) {
+	ab, ac := b.Sub(a), c.Sub(a)
+	p := ray.Direction.Cross(ac)
+	det := ab.Dot(p)
+	if math.Abs(det) < intersectEpsilon {
+		return 0, false
+	}
+
+	ao := ray.Origin.Sub(a)
+	u := ao.Dot(p) / det
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+
+	q := ao.Cross(ab)
+	v := ray.Direction.Dot(q) / det
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+
+	t := ac.Dot(q) / det
+	return t, t > intersectEpsilon
+}
+
+// IntersectBox finds where a ray first hits an axis-aligned box using the slab
+// method. Rays starting inside the box hit it where they leave. It returns the
+// distance t along the ray and false if the ray misses.
+func IntersectBox(ray Ray, b Box) (float64, bool) {
+	near, far := math.Inf(-1), math.Inf(1)
+	for axis := 0; axis < 3; axis++ {
+		if ray.Direction[axis] == 0 {
+			if ray.Origin[axis] < b.Min[axis] || ray.Origin[axis] > b.Max[axis] {
+				return 0, false
+			}
+			continue
+		}
+
+		t0 := (b.Min[axis] - ray.Origin[axis]) / ray.Direction[axis]
+		t1 := (b.Max[axis] - ray.Origin[axis]) / ray.Direction[axis]
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		near, far = math.Max(near, t0), math.Min(far, t1)
+		if near > far {
+			return 0, false
+		}
+	}
+
+	if near > intersectEpsilon {
+		return near, true
+	} else if far > intersectEpsilon {
+		return far, true
+	}
+	return 0, false
+}
@@ -0,0 +1,195 @@
+// gif provides animated GIFs built up from screens one frame at a time, so
+// that animations can be written without saving every frame and converting
+// them afterward.
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// GIFAnimation collects screens as the frames of an animated GIF. Each frame
+// is quantized to its own palette of at most 256 colors as it is added, and
+// shown for the Delay set when it was added. The animation loops forever.
+type GIFAnimation struct {
+	Delay time.Duration
+
+	frames []*image.Paletted
+	delays []int
+}
+
+// NewGIFAnimation creates an animation with no frames that shows each frame
+// for delay. It returns the new animation.
+func NewGIFAnimation(delay time.Duration) *GIFAnimation {
+	return &GIFAnimation{Delay: delay}
+}
+
+// Len returns the number of frames of the animation.
+func (a *GIFAnimation) Len() int {
+	return len(a.frames)
+}
+
+// AddFrame adds a screen as the next frame of the animation.
+func (a *GIFAnimation) AddFrame(screen [][][]int) {
+	a.frames = append(a.frames, quantize(screen))
+	a.delays = append(a.delays, int(a.Delay/(10*time.Millisecond)))
+}
+
+// Encode writes the animation to w as a GIF. It returns an error if the
+// animation has no frames or cannot be written.
+func (a *GIFAnimation) Encode(w io.Writer) error {
+	if len(a.frames) == 0 {
+		return fmt.Errorf("gif: animation has no frames")
+	}
+	return gif.EncodeAll(w, &gif.GIF{Image: a.frames, Delay: a.delays})
+}
+
+// Save writes the animation to the file named filename as a GIF. It returns
+// an error if the animation has no frames or the file cannot be written.
+func (a *GIFAnimation) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// colorBin counts the pixels of a screen whose colors fall in one cell of a
+// grid of 32 levels per channel, summing their colors so that the cell's mean
+// color can be found.
+type colorBin struct {
+	key     int
+	r, g, b int
+	n       int
+}
+
+// binColor returns an RGB color clamped to the range of a byte, along with
+// the key of the bin it falls in.
+func binColor(rgb []int) (key, r, g, b int) {
+	r, g, b = clampInt(rgb[0], 0, 255), clampInt(rgb[1], 0, 255), clampInt(rgb[2], 0, 255)
+	return r>>3<<10 | g>>3<<5 | b>>3, r, g, b
+}
+
+// quantize converts a screen to a paletted image of at most 256 colors chosen
+// by median cut: the colors of the screen are split again and again, at the
+// median of the channel they vary most in, into boxes of about as many
+// pixels each, and each box becomes the mean of its colors.
+func quantize(screen [][][]int) *image.Paletted {
+	bins := make(map[int]*colorBin)
+	for _, row := range screen {
+		for _, rgb := range row {
+			key, r, g, b := binColor(rgb)
+			bin, ok := bins[key]
+			if !ok {
+				bin = &colorBin{key: key}
+				bins[key] = bin
+			}
+			bin.r, bin.g, bin.b, bin.n = bin.r+r, bin.g+g, bin.b+b, bin.n+1
+		}
+	}
+
+	all := make([]*colorBin, 0, len(bins))
+	for _, bin := range bins {
+		all = append(all, bin)
+	}
+	boxes := [][]*colorBin{all}
+	for len(boxes) < 256 {
+		// Split the box covering the most pixels that still holds more than
+		// one bin.
+		split, most := -1, 0
+		for i, box := range boxes {
+			if n := binPixels(box); len(box) > 1 && n > most {
+				split, most = i, n
+			}
+		}
+		if split < 0 {
+			break
+		}
+
+		low, high := splitBox(boxes[split])
+		boxes[split] = low
+		boxes = append(boxes, high)
+	}
+
+	palette := make(color.Palette, len(boxes))
+	index := make(map[int]uint8, len(bins))
+	for i, box := range boxes {
+		var r, g, b, n int
+		for _, bin := range box {
+			r, g, b, n = r+bin.r, g+bin.g, b+bin.b, n+bin.n
+			index[bin.key] = uint8(i)
+		}
+		if n > 0 {
+			palette[i] = color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+		} else {
+			palette[i] = color.RGBA{A: 255}
+		}
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, screenWidth(screen), len(screen)), palette)
+	for y, row := range screen {
+		for x, rgb := range row {
+			key, _, _, _ := binColor(rgb)
+			img.SetColorIndex(x, y, index[key])
+		}
+	}
+	return img
+}
+
+// binPixels returns the number of pixels in a box of bins.
+func binPixels(box []*colorBin) int {
+	n := 0
+	for _, bin := range box {
+		n += bin.n
+	}
+	return n
+}
+
+// splitBox splits a box of at least two bins in two at the median pixel along
+// the channel its bins span the widest range of.
+func splitBox(box []*colorBin) (low, high []*colorBin) {
+	channel := func(bin *colorBin, c int) int {
+		return bin.key >> (10 - 5*c) & 31
+	}
+
+	widest, widestRange := 0, -1
+	for c := 0; c < 3; c++ {
+		min, max := 31, 0
+		for _, bin := range box {
+			v := channel(bin, c)
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		if max-min > widestRange {
+			widest, widestRange = c, max-min
+		}
+	}
+
+	sort.Slice(box, func(i, j int) bool {
+		return channel(box[i], widest) < channel(box[j], widest)
+	})
+
+	half, seen := binPixels(box)/2, 0
+	for i, bin := range box[:len(box)-1] {
+		seen += bin.n
+		if seen >= half {
+			return box[:i+1], box[i+1:]
+		}
+	}
+	return box[:len(box)-1], box[len(box)-1:]
+}
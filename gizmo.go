@@ -0,0 +1,84 @@
+// gizmo provides on-screen gizmos for translating, rotating, and scaling a
+// transformed object.
+package main
+
+import (
+	"math"
+)
+
+// GizmoKind is the kind of transform a gizmo edits.
+type GizmoKind int
+
+const (
+	// TranslateGizmo is drawn as an arrow along each axis.
+	TranslateGizmo GizmoKind = iota
+	// RotateGizmo is drawn as a ring around each axis.
+	RotateGizmo
+	// ScaleGizmo is drawn as a line ending in a box along each axis.
+	ScaleGizmo
+)
+
+// gizmoColors are the colors of the x, y, and z axes of a gizmo.
+var gizmoColors = [3][]int{{255, 0, 0}, {0, 200, 0}, {0, 0, 255}}
+
+// Gizmo holds the edges of a gizmo for each of the x, y, and z axes.
+type Gizmo [3]EdgeMatrix
+
+// NewGizmo creates a gizmo of the given kind and size placed at the origin of
+// transform, such as the transform of a selected object. The gizmo's axes stay
+// aligned with the world axes. It returns the new gizmo.
+func NewGizmo(kind GizmoKind, transform [][]float64, size float64) Gizmo {
+	origin := Vector{transform[0][3], transform[1][3], transform[2][3]}
+
+	var g Gizmo
+	for axis := range g {
+		g[axis] = NewEdgeMatrix()
+
+		var e, p, q Vector
+		e[axis], p[(axis+1)%3], q[(axis+2)%3] = 1, 1, 1
+		tip := origin.Add(e.Scale(size))
+
+		switch kind {
+		case TranslateGizmo:
+			addVectorEdge(g[axis], origin, tip)
+			back := tip.Sub(e.Scale(size * 0.15))
+			addVectorEdge(g[axis], tip, back.Add(p.Scale(size*0.07)))
+			addVectorEdge(g[axis], tip, back.Sub(p.Scale(size*0.07)))
+			addVectorEdge(g[axis], tip, back.Add(q.Scale(size*0.07)))
+			addVectorEdge(g[axis], tip, back.Sub(q.Scale(size*0.07)))
+		case RotateGizmo:
+			segments := 64
+			for i := 0; i < segments; i++ {
+				t0 := 2 * math.Pi * float64(i) / float64(segments)
+				t1 := 2 * math.Pi * float64(i+1) / float64(segments)
+				a := origin.Add(p.Scale(size * math.Cos(t0))).Add(q.Scale(size * math.Sin(t0)))
+				b := origin.Add(p.Scale(size * math.Cos(t1))).Add(q.Scale(size * math.Sin(t1)))
+				addVectorEdge(g[axis], a, b)
+			}
+		case ScaleGizmo:
+			addVectorEdge(g[axis], origin, tip)
+			h := size * 0.05
+			g[axis].AddBox(tip[0]-h, tip[1]+h, tip[2]+h, 2*h, 2*h, 2*h)
+		}
+	}
+	return g
+}
+
+// Draw draws every axis of the gizmo in its own color with a renderer.
+func (g Gizmo) Draw(r *Renderer) error {
+	color := r.Color
+	defer func() { r.Color = color }()
+
+	for axis, edges := range g {
+		r.Color = gizmoColors[axis]
+		if err := r.DrawLines(edges); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addVectorEdge adds the edge from a to b to an edge matrix.
+func addVectorEdge(m EdgeMatrix, a, b Vector) {
+	m.AddEdge(a[0], a[1], a[2], b[0], b[1], b[2])
+}
@@ -0,0 +1,180 @@
+// isosurface provides extraction of isosurfaces, the surfaces where a scalar
+// field over space equals a level, as triangle meshes.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// cubeTetrahedra splits a cube into six tetrahedra around its diagonal from
+// corner 0 to corner 6. Corner c of the cube is offset by (c&1 ^ c>>1&1,
+// c>>1&1, c>>2&1) cells, so corners 0 to 3 run around the bottom face and 4 to 7
+// around the top.
+var cubeTetrahedra = [6][4]int{
+	{0, 6, 1, 2}, {0, 6, 2, 3}, {0, 6, 3, 7},
+	{0, 6, 7, 4}, {0, 6, 4, 5}, {0, 6, 5, 1},
+}
+
+// Isosurface extracts the surface where the values of a grid equal level.
+// grid[i][j][k] is the value at (i*cell, j*cell, k*cell). The field is
+// treated as inside the surface where it is below level, as with signed
+// distances, and faces wind counterclockwise seen from outside.
+//
+// This is the marching tetrahedra variant of marching cubes: every cube of
+// the grid is split into six tetrahedra, which needs no case tables and has no
+// ambiguous cases, so the surface never has holes. Vertices on edges shared by
+// neighboring cubes are shared in the mesh. It returns an error if the grid is
+// not a box of values or cell is not positive.
+func Isosurface(grid [][][]float64, cell, level float64) (Mesh, error) {
+	if !(cell > 0) || math.IsInf(cell, 1) {
+		return Mesh{}, fmt.Errorf("isosurface: cell size %g is not a positive number", cell)
+	}
+	nx, ny, nz := len(grid), 0, 0
+	if nx > 0 {
+		ny = len(grid[0])
+	}
+	if ny > 0 {
+		nz = len(grid[0][0])
+	}
+	for i := range grid {
+		if len(grid[i]) != ny {
+			return Mesh{}, fmt.Errorf("isosurface: grid[%d] has %d rows, want %d", i, len(grid[i]), ny)
+		}
+		for j := range grid[i] {
+			if len(grid[i][j]) != nz {
+				return Mesh{}, fmt.Errorf("isosurface: grid[%d][%d] has %d values, want %d", i, j, len(grid[i][j]), nz)
+			}
+		}
+	}
+
+	var m Mesh
+	shared := make(map[[2]int]int)
+	id := func(i, j, k int) int { return (i*ny+j)*nz + k }
+
+	for i := 0; i+1 < nx; i++ {
+		for j := 0; j+1 < ny; j++ {
+			for k := 0; k+1 < nz; k++ {
+				var ids [8]int
+				var points [8]Vector
+				var values [8]float64
+				for c := 0; c < 8; c++ {
+					dy, dz := c>>1&1, c>>2&1
+					dx := c&1 ^ dy
+					ids[c] = id(i+dx, j+dy, k+dz)
+					points[c] = Vector{float64(i+dx) * cell, float64(j+dy) * cell, float64(k+dz) * cell}
+					values[c] = grid[i+dx][j+dy][k+dz]
+				}
+
+				for _, t := range cubeTetrahedra {
+					m.marchTetrahedron(shared, t, ids, points, values, level)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// IsosurfaceFunc extracts the surface where field equals level within the box
+// from min to max, sampling field on a grid with cells steps along the box's
+// longest side. It returns the surface in the same form as Isosurface.
+func IsosurfaceFunc(field func(p Vector) float64, min, max Vector, cells int, level float64) (Mesh, error) {
+	size := max.Sub(min)
+	longest := math.Max(size[0], math.Max(size[1], size[2]))
+	if cells < 1 || !(longest > 0) {
+		return Mesh{}, fmt.Errorf("isosurface: cannot sample %d cells over an empty box", cells)
+	}
+
+	cell := longest / float64(cells)
+	grid := make([][][]float64, int(math.Ceil(size[0]/cell))+1)
+	for i := range grid {
+		grid[i] = make([][]float64, int(math.Ceil(size[1]/cell))+1)
+		for j := range grid[i] {
+			grid[i][j] = make([]float64, int(math.Ceil(size[2]/cell))+1)
+			for k := range grid[i][j] {
+				grid[i][j][k] = field(min.Add(Vector{float64(i), float64(j), float64(k)}.Scale(cell)))
+			}
+		}
+	}
+
+	m, err := Isosurface(grid, cell, level)
+	for i, v := range m.Vertices {
+		m.Vertices[i] = v.Add(min)
+	}
+	return m, err
+}
+
+// marchTetrahedron adds the piece of the surface at level crossing the
+// tetrahedron with the corners t of a cube to the mesh. The vertex on the edge
+// between grid points a and b is stored in shared under {a, b} with the
+// smaller first, or under {p, p} if it falls on grid point p, so neighboring
+// pieces reuse it. Pieces that collapse because the field equals level at a grid point are
+// dropped.
+func (m *Mesh) marchTetrahedron(shared map[[2]int]int, t [4]int, ids [8]int, points [8]Vector, values [8]float64, level float64) {
+	var inside, outside []int
+	for _, c := range t {
+		if values[c] < level {
+			inside = append(inside, c)
+		} else {
+			outside = append(outside, c)
+		}
+	}
+	if len(inside) == 0 || len(outside) == 0 {
+		return
+	}
+
+	vertex := func(a, b int) int {
+		if ids[b] < ids[a] {
+			a, b = b, a
+		}
+		s := (level - values[a]) / (values[b] - values[a])
+		key := [2]int{ids[a], ids[b]}
+		if s <= 0 {
+			key[1] = ids[a]
+		} else if s >= 1 {
+			key[0] = ids[b]
+		}
+		if v, ok := shared[key]; ok {
+			return v
+		}
+
+		shared[key] = len(m.Vertices)
+		m.Vertices = append(m.Vertices, points[a].Add(points[b].Sub(points[a]).Scale(s)))
+		return shared[key]
+	}
+
+	var in, out Vector
+	for _, c := range inside {
+		in = in.Add(points[c].Scale(1 / float64(len(inside))))
+	}
+	for _, c := range outside {
+		out = out.Add(points[c].Scale(1 / float64(len(outside))))
+	}
+	outward := out.Sub(in)
+
+	addFace := func(a, b, c int) {
+		if a == b || b == c || a == c {
+			return
+		}
+		pa, pb, pc := m.Vertices[a], m.Vertices[b], m.Vertices[c]
+		if pb.Sub(pa).Cross(pc.Sub(pa)).Dot(outward) < 0 {
+			b, c = c, b
+		}
+		m.Faces = append(m.Faces, [3]int{a, b, c})
+	}
+
+	switch {
+	case len(inside) == 1:
+		a := inside[0]
+		addFace(vertex(a, outside[0]), vertex(a, outside[1]), vertex(a, outside[2]))
+	case len(outside) == 1:
+		b := outside[0]
+		addFace(vertex(inside[0], b), vertex(inside[1], b), vertex(inside[2], b))
+	default:
+		p, q := inside[0], inside[1]
+		r, s := outside[0], outside[1]
+		pr, ps, qr, qs := vertex(p, r), vertex(p, s), vertex(q, r), vertex(q, s)
+		addFace(pr, ps, qs)
+		addFace(pr, qs, qr)
+	}
+}
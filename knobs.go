@@ -0,0 +1,106 @@
+// knobs provides the symbol table of an animation, which maps the names of its
+// knobs to their values in every frame, so that scripts and Go code can drive
+// animated parameters such as rotation angles and scales alike.
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// KnobTable holds the values of the knobs of an animation in each of its
+// frames. A knob has no value until the first frame it is set in, and keeps
+// the last value it was set to in the frames after.
+type KnobTable struct {
+	frames int
+	values map[string][]float64
+	set    map[string][]bool
+}
+
+// NewKnobTable creates a table for an animation of the given number of
+// frames, with no knobs. It returns the new table.
+func NewKnobTable(frames int) *KnobTable {
+	return &KnobTable{
+		frames: frames,
+		values: make(map[string][]float64),
+		set:    make(map[string][]bool),
+	}
+}
+
+// Frames returns the number of frames of the table's animation.
+func (t *KnobTable) Frames() int {
+	return t.frames
+}
+
+// Set sets a knob to v in a frame. It returns an error if the frame is not one
+// of the animation's.
+func (t *KnobTable) Set(knob string, frame int, v float64) error {
+	if frame < 0 || frame >= t.frames {
+		return fmt.Errorf("knob %q: frame %d is not one of the %d frames", knob, frame, t.frames)
+	}
+
+	if t.values[knob] == nil {
+		t.values[knob] = make([]float64, t.frames)
+		t.set[knob] = make([]bool, t.frames)
+	}
+	t.values[knob][frame], t.set[knob][frame] = v, true
+	return nil
+}
+
+// Vary moves a knob steadily from start in frame first to end in frame last,
+// setting it in every frame between. It returns an error if the frames run
+// backward or are not all the animation's.
+func (t *KnobTable) Vary(knob string, first, last int, start, end float64) error {
+	return t.VaryEased(knob, first, last, start, end, Linear)
+}
+
+// VaryEased is like Vary, but moves the knob from start to end as ease says
+// instead of steadily.
+func (t *KnobTable) VaryEased(knob string, first, last int, start, end float64, ease Easing) error {
+	if last < first {
+		return fmt.Errorf("knob %q: frames %d to %d run backward", knob, first, last)
+	} else if first < 0 || last >= t.frames {
+		return fmt.Errorf("knob %q: frames %d to %d are not all of the %d frames", knob, first, last, t.frames)
+	}
+
+	for frame := first; frame <= last; frame++ {
+		s := 0.0
+		if last > first {
+			s = float64(frame-first) / float64(last-first)
+		}
+		t.Set(knob, frame, start+ease(s)*(end-start))
+	}
+	return nil
+}
+
+// Value returns the value of a knob in a frame, and whether it has one there.
+func (t *KnobTable) Value(knob string, frame int) (float64, bool) {
+	set := t.set[knob]
+	for frame = clampInt(frame, -1, len(set)-1); frame >= 0; frame-- {
+		if set[frame] {
+			return t.values[knob][frame], true
+		}
+	}
+	return 0, false
+}
+
+// Frame returns the values of every knob with a value in a frame, by name.
+func (t *KnobTable) Frame(frame int) map[string]float64 {
+	values := make(map[string]float64, len(t.values))
+	for knob := range t.values {
+		if v, ok := t.Value(knob, frame); ok {
+			values[knob] = v
+		}
+	}
+	return values
+}
+
+// Names returns the names of the table's knobs in sorted order.
+func (t *KnobTable) Names() []string {
+	names := make([]string, 0, len(t.values))
+	for knob := range t.values {
+		names = append(names, knob)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,294 @@
+// lod provides mesh simplification by quadric error metrics and selection
+// between levels of detail by distance from the camera.
+package main
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// boundaryWeight is how much more moving a vertex off a boundary edge costs
+// than moving it off a face's plane.
+const boundaryWeight = 1000
+
+// quadric is the symmetric 4x4 matrix of a quadric error metric.
+type quadric [4][4]float64
+
+// add returns the sum of two quadrics.
+func (q quadric) add(r quadric) quadric {
+	for i := range q {
+		for j := range q[i] {
+			q[i][j] += r[i][j]
+		}
+	}
+	return q
+}
+
+// cost returns the squared distance error of moving a vertex to v.
+func (q quadric) cost(v Vector) float64 {
+	p := [4]float64{v[0], v[1], v[2], 1}
+	total := 0.0
+	for i := range q {
+		for j := range q[i] {
+			total += p[i] * q[i][j] * p[j]
+		}
+	}
+	return total
+}
+
+// planeQuadric returns the quadric of the plane through the triangle a, b, c.
+func planeQuadric(a, b, c Vector) quadric {
+	n := b.Sub(a).Cross(c.Sub(a)).Normalize()
+	p := [4]float64{n[0], n[1], n[2], -n.Dot(a)}
+
+	var q quadric
+	for i := range q {
+		for j := range q[i] {
+			q[i][j] = p[i] * p[j]
+		}
+	}
+	return q
+}
+
+// collapse is a candidate collapse of the edge from vertex u to vertex v into
+// a single vertex at target.
+type collapse struct {
+	u, v    int
+	target  Vector
+	cost    float64
+	version int
+}
+
+// collapseHeap orders candidate collapses from cheapest to most expensive.
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(collapse)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Simplify returns a copy of the mesh reduced to at most faces faces by
+// repeatedly collapsing the edge whose removal changes the surface least, as
+// measured by quadric error metrics. Vertices should be welded first so that
+// neighboring faces share vertices.
+func (m Mesh) Simplify(faces int) Mesh {
+	vertices := append([]Vector{}, m.Vertices...)
+	tris := append([][3]int{}, m.Faces...)
+	aliveFaces := make([]bool, len(tris))
+	quadrics := make([]quadric, len(vertices))
+	incident := make([][]int, len(vertices))
+	versions := make([]int, len(vertices))
+
+	alive := 0
+	for i, f := range tris {
+		if m.degenerate(i) {
+			continue
+		}
+		aliveFaces[i] = true
+		alive++
+		q := planeQuadric(vertices[f[0]], vertices[f[1]], vertices[f[2]])
+		for _, v := range f {
+			quadrics[v] = quadrics[v].add(q)
+			incident[v] = append(incident[v], i)
+		}
+	}
+
+	// Constrain boundary edges with steep planes so the outline of open
+	// meshes survives.
+	for e, fs := range m.edgeFaces() {
+		if len(fs) != 1 || !aliveFaces[fs[0]] {
+			continue
+		}
+		f := tris[fs[0]]
+		a, b, c := vertices[f[0]], vertices[f[1]], vertices[f[2]]
+		normal := b.Sub(a).Cross(c.Sub(a))
+		p, q := vertices[e[0]], vertices[e[1]]
+		if q.Sub(p).Cross(normal).Length() == 0 {
+			continue
+		}
+
+		constraint := planeQuadric(p, q, p.Add(normal))
+		for i := range constraint {
+			for j := range constraint[i] {
+				constraint[i][j] *= boundaryWeight
+			}
+		}
+		quadrics[e[0]] = quadrics[e[0]].add(constraint)
+		quadrics[e[1]] = quadrics[e[1]].add(constraint)
+	}
+
+	h := &collapseHeap{}
+	consider := func(u, v int) {
+		q := quadrics[u].add(quadrics[v])
+		best := collapse{u: u, v: v, version: versions[u] + versions[v]}
+		for i, target := range []Vector{vertices[u].Add(vertices[v]).Scale(0.5), vertices[u], vertices[v]} {
+			if cost := q.cost(target); i == 0 || cost < best.cost {
+				best.target, best.cost = target, cost
+			}
+		}
+		heap.Push(h, best)
+	}
+
+	for i, f := range tris {
+		if aliveFaces[i] {
+			for j := 0; j < 3; j++ {
+				if f[j] < f[(j+1)%3] {
+					consider(f[j], f[(j+1)%3])
+				}
+			}
+		}
+	}
+
+	for alive > faces && h.Len() > 0 {
+		c := heap.Pop(h).(collapse)
+		if c.version != versions[c.u]+versions[c.v] {
+			continue
+		}
+		if flipsFaces(vertices, tris, aliveFaces, incident, c) {
+			continue
+		}
+
+		// Merge v into u.
+		vertices[c.u] = c.target
+		quadrics[c.u] = quadrics[c.u].add(quadrics[c.v])
+		versions[c.u]++
+		versions[c.v]++
+
+		for _, fi := range incident[c.v] {
+			if !aliveFaces[fi] {
+				continue
+			}
+			for j := range tris[fi] {
+				if tris[fi][j] == c.v {
+					tris[fi][j] = c.u
+				}
+			}
+			f := tris[fi]
+			if f[0] == f[1] || f[1] == f[2] || f[0] == f[2] {
+				aliveFaces[fi] = false
+				alive--
+			} else {
+				incident[c.u] = append(incident[c.u], fi)
+			}
+		}
+		incident[c.v] = nil
+
+		neighbors := make(map[int]bool)
+		for _, fi := range incident[c.u] {
+			if aliveFaces[fi] {
+				for _, w := range tris[fi] {
+					if w != c.u {
+						neighbors[w] = true
+					}
+				}
+			}
+		}
+		for w := range neighbors {
+			versions[w]++
+		}
+		for w := range neighbors {
+			consider(c.u, w)
+		}
+	}
+
+	simplified := Mesh{Vertices: vertices, Colors: m.Colors}
+	for i, f := range tris {
+		if aliveFaces[i] {
+			simplified.Faces = append(simplified.Faces, f)
+		}
+	}
+	return simplified.compact()
+}
+
+// flipsFaces reports whether collapsing an edge would turn any face that
+// survives the collapse to face the opposite way.
+func flipsFaces(vertices []Vector, tris [][3]int, aliveFaces []bool, incident [][]int, c collapse) bool {
+	for _, end := range []int{c.u, c.v} {
+		for _, fi := range incident[end] {
+			f := tris[fi]
+			if !aliveFaces[fi] || hasVertex(f, c.u) && hasVertex(f, c.v) {
+				continue
+			}
+
+			before := [3]Vector{vertices[f[0]], vertices[f[1]], vertices[f[2]]}
+			after := before
+			for j, v := range f {
+				if v == end {
+					after[j] = c.target
+				}
+			}
+
+			n0 := before[1].Sub(before[0]).Cross(before[2].Sub(before[0]))
+			n1 := after[1].Sub(after[0]).Cross(after[2].Sub(after[0]))
+			if n0.Dot(n1) <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasVertex reports whether face f uses vertex v.
+func hasVertex(f [3]int, v int) bool {
+	return f[0] == v || f[1] == v || f[2] == v
+}
+
+// LOD is a set of versions of a mesh at decreasing levels of detail, each used
+// from a minimum viewing distance onward.
+type LOD struct {
+	Levels    []Mesh
+	Distances []float64
+}
+
+// NewLOD creates a level of detail set from a mesh. Level 0 is the mesh itself,
+// used from distance 0. Every following level keeps the given fraction of the
+// previous level's faces and is used from the next of distances onward. It
+// returns the new set.
+func NewLOD(m Mesh, fraction float64, distances ...float64) LOD {
+	sorted := append([]float64{}, distances...)
+	sort.Float64s(sorted)
+
+	lod := LOD{Levels: []Mesh{m}, Distances: []float64{0}}
+	for _, d := range sorted {
+		previous := lod.Levels[len(lod.Levels)-1]
+		target := int(float64(len(previous.Faces)) * fraction)
+		lod.Levels = append(lod.Levels, previous.Simplify(target))
+		lod.Distances = append(lod.Distances, d)
+	}
+	return lod
+}
+
+// viewDistance returns how far the point p lies in front of the renderer's
+// camera along its line of sight, if the camera is a perspective camera, as
+// made by Camera.Matrix or MakePerspective. The w such a camera gives a point
+// is its distance scaled by the length of the x, y, and z of the camera's last
+// row. It returns false for parallel cameras, which have no eye.
+func (r *Renderer) viewDistance(p Vector) (float64, bool) {
+	c := r.Camera
+	if len(c) < 4 || len(c[3]) < 4 {
+		return 0, false
+	}
+	scale := Vector{c[3][0], c[3][1], c[3][2]}.Length()
+	if scale == 0 {
+		return 0, false
+	}
+	w := c[3][0]*p[0] + c[3][1]*p[1] + c[3][2]*p[2] + c[3][3]
+	return w / scale, true
+}
+
+// Select returns the level of detail to draw at the given viewing distance.
+func (l LOD) Select(distance float64) Mesh {
+	level := 0
+	for i, d := range l.Distances {
+		if distance >= d {
+			level = i
+		}
+	}
+	return l.Levels[level]
+}
@@ -0,0 +1,110 @@
+// logger provides a pluggable, leveled logger for diagnostics such as bad
+// curves or clipped geometry. Nothing is logged unless a logger is set.
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the lowercase name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// Field is a key-value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives log messages along with their level and fields.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// nopLogger is a Logger that discards every message.
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...Field) {}
+
+// writerLogger is a Logger that writes messages at or above a level to a
+// writer, one per line.
+type writerLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min Level
+}
+
+// NewWriterLogger creates a Logger that writes every message at or above min
+// to w as a line of the form "level msg key=value ...". It returns the new
+// logger.
+func NewWriterLogger(w io.Writer, min Level) Logger {
+	return &writerLogger{w: w, min: min}
+}
+
+func (l *writerLogger) Log(level Level, msg string, fields ...Field) {
+	if level < l.min {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	b.WriteString("\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+// logger is the Logger every diagnostic is sent to.
+var logger = struct {
+	sync.RWMutex
+	l Logger
+}{l: nopLogger{}}
+
+// SetLogger makes l receive every diagnostic. A nil l discards them.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+
+	logger.Lock()
+	defer logger.Unlock()
+	logger.l = l
+}
+
+// logf sends a message to the current logger.
+func logf(level Level, msg string, fields ...Field) {
+	logger.RLock()
+	l := logger.l
+	logger.RUnlock()
+	l.Log(level, msg, fields...)
+}
@@ -1,9 +1,13 @@
 package main
 
-func main() {
-	screen := NewScreen()
-	transform := make([][]float64, 0)
-	edges := make([][]float64, 4)
+import "os"
 
-	ParseFile("script", transform, edges, screen)
+// main runs the script named by the first argument, or the file named script
+// if there is none.
+func main() {
+	filename := "script"
+	if len(os.Args) > 1 {
+		filename = os.Args[1]
+	}
+	ParseFile(filename, NewRenderer())
 }
@@ -55,9 +55,14 @@ func MakeHermite() [][]float64 {
 }
 
 // MultiplyMatrices multiples two matrices and stores it in the second matrix
-// given.
-func MultiplyMatrices(m1Ptr, m2Ptr *[][]float64) {
+// given. It returns an error, leaving the second matrix unchanged, if the
+// matrices cannot be multiplied.
+func MultiplyMatrices(m1Ptr, m2Ptr *[][]float64) error {
 	m1, m2 := *m1Ptr, *m2Ptr
+	if err := checkMultipliable(m1, m2); err != nil {
+		return err
+	}
+
 	product := NewMatrix(len(m1), len(m2[0]))
 
 	for i, row := range m1 {
@@ -68,6 +73,73 @@ func MultiplyMatrices(m1Ptr, m2Ptr *[][]float64) {
 	}
 
 	*m2Ptr = product
+	return nil
+}
+
+// checkMultipliable checks that m1 and m2 are rectangular and that m1 has as
+// many columns as m2 has rows. It returns an error describing the problem
+// otherwise.
+func checkMultipliable(m1, m2 [][]float64) error {
+	if len(m1) == 0 || len(m2) == 0 {
+		return fmt.Errorf("cannot multiply a %dx? matrix by a %dx? matrix", len(m1), len(m2))
+	}
+	for _, m := range [][][]float64{m1, m2} {
+		for _, row := range m {
+			if len(row) != len(m[0]) {
+				return fmt.Errorf("matrix rows have %d and %d columns", len(m[0]), len(row))
+			}
+		}
+	}
+	if len(m1[0]) != len(m2) {
+		return fmt.Errorf("cannot multiply a %dx%d matrix by a %dx%d matrix",
+			len(m1), len(m1[0]), len(m2), len(m2[0]))
+	}
+	return nil
+}
+
+// InvertMatrix calculates the inverse of a square matrix by Gauss-Jordan
+// elimination. It returns an error if the matrix is not square or is singular.
+func InvertMatrix(matrix [][]float64) ([][]float64, error) {
+	n := len(matrix)
+	a := NewMatrix(n, 2*n)
+	for i, row := range matrix {
+		if len(row) != n {
+			return nil, fmt.Errorf("cannot invert a matrix with %d rows and %d columns", n, len(row))
+		}
+		copy(a[i], row)
+		a[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(a[i][col]) > math.Abs(a[pivot][col]) {
+				pivot = i
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, fmt.Errorf("cannot invert a singular matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		scale := a[col][col]
+		for j := range a[col] {
+			a[col][j] /= scale
+		}
+		for i := range a {
+			if f := a[i][col]; i != col && f != 0 {
+				for j := range a[i] {
+					a[i][j] -= f * a[col][j]
+				}
+			}
+		}
+	}
+
+	inverse := NewMatrix(n, n)
+	for i := range inverse {
+		copy(inverse[i], a[i][n:])
+	}
+	return inverse, nil
 }
 
 // ExtractColumn extracts the column of a matrix. It returns that column as
@@ -82,6 +154,15 @@ func ExtractColumn(matrix [][]float64, colIndex int) []float64 {
 	return col
 }
 
+// SurfaceNormal calculates the normal of the triangle whose corners are
+// columns i, i+1, and i+2 of a matrix. The normal faces the side the corners
+// run counterclockwise around, and its length is twice the triangle's area. It
+// returns the normal.
+func SurfaceNormal(matrix [][]float64, i int) Vector {
+	a, b, c := columnVector(matrix, i), columnVector(matrix, i+1), columnVector(matrix, i+2)
+	return b.Sub(a).Cross(c.Sub(a))
+}
+
 // dot receives two slices as vectors. It returns their dot product.
 func dot(x, y []float64) float64 {
 	output := 0.0
@@ -92,7 +173,7 @@ func dot(x, y []float64) float64 {
 }
 
 // NewMatrix creates a new float64 matrix. The default row and column size is 4.
-// It returns the new matrix.
+// Negative sizes are treated as 0. It returns the new matrix.
 func NewMatrix(params ...int) [][]float64 {
 	rows := 4
 	cols := 4
@@ -101,6 +182,12 @@ func NewMatrix(params ...int) [][]float64 {
 		rows = params[0]
 		cols = params[1]
 	}
+	if rows < 0 {
+		rows = 0
+	}
+	if cols < 0 {
+		cols = 0
+	}
 
 	matrix := make([][]float64, rows)
 	for i, _ := range matrix {
@@ -110,6 +197,22 @@ func NewMatrix(params ...int) [][]float64 {
 	return matrix
 }
 
+// DeepCopy copies every row of a matrix into a new matrix, so that changing the
+// copy never changes the original. It returns the copy.
+func DeepCopy(matrix [][]float64) [][]float64 {
+	if matrix == nil {
+		return nil
+	}
+
+	c := make([][]float64, len(matrix))
+	for i, row := range matrix {
+		c[i] = make([]float64, len(row))
+		copy(c[i], row)
+	}
+
+	return c
+}
+
 // PrintMatrix prints a float64 matrix.
 func PrintMatrix(matrix [][]float64) {
 	output := ""
@@ -127,7 +230,7 @@ func PrintMatrix(matrix [][]float64) {
 }
 
 // MakeTranslationMatrix creates a translation matrix using x, y, and z as the
-// translation offsets.
+// translation offsets. It panics if params holds fewer than 3 numbers.
 func MakeTranslationMatrix(params ...float64) (m [][]float64) {
 	m = NewMatrix()
 	MakeIdentity(m)
@@ -135,8 +238,38 @@ func MakeTranslationMatrix(params ...float64) (m [][]float64) {
 	return
 }
 
+// MakeTranslate creates a translation matrix that moves points by dx, dy, and
+// dz. It returns the translation matrix.
+func MakeTranslate(dx, dy, dz float64) [][]float64 {
+	return MakeTranslationMatrix(dx, dy, dz)
+}
+
+// MakeScale creates a dilation matrix that scales points by sx, sy, and sz
+// about the origin. It returns the dilation matrix.
+func MakeScale(sx, sy, sz float64) [][]float64 {
+	return MakeDilationMatrix(sx, sy, sz)
+}
+
+// ApplyMatrix multiplies the 4x4 transform m into the points of an edge or
+// polygon matrix in place. It returns an error, leaving the points unchanged,
+// if m is not a 4x4 matrix or the matrices cannot be multiplied.
+func ApplyMatrix(m [][]float64, points [][]float64) error {
+	if _, err := NewTransform(m); err != nil {
+		return err
+	}
+
+	product := points
+	if err := MultiplyMatrices(&m, &product); err != nil {
+		return err
+	}
+	for i := range points {
+		copy(points[i], product[i])
+	}
+	return nil
+}
+
 // MakeDilationMatrix creates a dilation matrix using x, y, and z as the
-// dilation offsets.
+// dilation offsets. It panics if params holds fewer than 3 numbers.
 func MakeDilationMatrix(params ...float64) (m [][]float64) {
 	m = NewMatrix()
 	MakeIdentity(m)
@@ -146,9 +279,9 @@ func MakeDilationMatrix(params ...float64) (m [][]float64) {
 
 // MakeRotX creates a rotation matrix using theta as the angle of rotation and
 // X as the axis of rotation. It returns the rotation matrix.
-func MakeRotX(theta float64) (m [][]float64) {
+func MakeRotX(theta Angle) (m [][]float64) {
 	m = NewMatrix()
-	radians := theta / 180 * math.Pi
+	radians := theta.Radians()
 	sin, cos := math.Sin(radians), math.Cos(radians)
 	MakeIdentity(m)
 	m[1][1], m[1][2] = cos, -sin
@@ -158,9 +291,9 @@ func MakeRotX(theta float64) (m [][]float64) {
 
 // MakeRotY creates a rotation matrix using theta as the angle of rotation and
 // Y as the axis of rotation. It returns the rotation matrix.
-func MakeRotY(theta float64) (m [][]float64) {
+func MakeRotY(theta Angle) (m [][]float64) {
 	m = NewMatrix()
-	radians := theta / 180 * math.Pi
+	radians := theta.Radians()
 	sin, cos := math.Sin(radians), math.Cos(radians)
 	MakeIdentity(m)
 	m[0][0], m[0][2] = cos, sin
@@ -170,12 +303,40 @@ func MakeRotY(theta float64) (m [][]float64) {
 
 // MakeRotZ creates a rotation matrix using theta as the angle of rotation and
 // Z as the axis of rotation. It returns the rotation matrix.
-func MakeRotZ(theta float64) (m [][]float64) {
+func MakeRotZ(theta Angle) (m [][]float64) {
 	m = NewMatrix()
-	radians := theta / 180 * math.Pi
+	radians := theta.Radians()
 	sin, cos := math.Sin(radians), math.Cos(radians)
 	MakeIdentity(m)
 	m[0][0], m[0][1] = cos, -sin
 	m[1][0], m[1][1] = sin, cos
 	return
 }
+
+// MakeRotate creates a rotation matrix using theta as the angle of rotation and
+// the vector (axisX, axisY, axisZ) as the axis of rotation, following
+// Rodrigues' rotation formula. The rotation is counterclockwise when looking
+// back along the axis toward the origin. A zero axis gives the identity. It
+// returns the rotation matrix.
+func MakeRotate(axisX, axisY, axisZ float64, theta Angle) (m [][]float64) {
+	m = NewMatrix()
+	MakeIdentity(m)
+	k := Vector{axisX, axisY, axisZ}.Normalize()
+	if k.Length() == 0 {
+		return
+	}
+
+	radians := theta.Radians()
+	sin, cos := math.Sin(radians), math.Cos(radians)
+	cross := [3][3]float64{
+		{0, -k[2], k[1]},
+		{k[2], 0, -k[0]},
+		{-k[1], k[0], 0},
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			m[i][j] = cos*m[i][j] + sin*cross[i][j] + (1-cos)*k[i]*k[j]
+		}
+	}
+	return
+}
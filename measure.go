@@ -0,0 +1,73 @@
+// measure provides functions for measuring lengths, areas, and volumes of
+// generated geometry.
+package main
+
+import (
+	"math"
+)
+
+// EdgeLength returns the total length of every edge of an edge matrix.
+func EdgeLength(edges EdgeMatrix) float64 {
+	length := 0.0
+	for i := 0; i < edges.Len()-1; i += 2 {
+		length += columnVector(edges, i+1).Sub(columnVector(edges, i)).Length()
+	}
+	return length
+}
+
+// ArcLength returns the length of the path through every point of an edge
+// matrix in order, such as the points added by AddCurve or AddCircle.
+func ArcLength(edges EdgeMatrix) float64 {
+	length := 0.0
+	for i := 1; i < edges.Len(); i++ {
+		length += columnVector(edges, i).Sub(columnVector(edges, i-1)).Length()
+	}
+	return length
+}
+
+// PolygonArea returns the area of the planar polygon whose corners are points,
+// in order. The polygon may face any direction.
+func PolygonArea(points []Vector) float64 {
+	var normal Vector
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		normal = normal.Add(p.Cross(q))
+	}
+	return normal.Length() / 2
+}
+
+// SurfaceArea returns the total area of the triangles of a matrix whose every
+// three consecutive columns are the corners of a triangle.
+func SurfaceArea(triangles [][]float64) float64 {
+	area := 0.0
+	for i := 0; i+2 < columnCount(triangles); i += 3 {
+		a, b, c := columnVector(triangles, i), columnVector(triangles, i+1), columnVector(triangles, i+2)
+		area += b.Sub(a).Cross(c.Sub(a)).Length() / 2
+	}
+	return area
+}
+
+// Volume returns the volume enclosed by the triangles of a matrix whose every
+// three consecutive columns are the corners of a triangle. The triangles must
+// form a closed mesh with consistently wound corners.
+func Volume(triangles [][]float64) float64 {
+	volume := 0.0
+	for i := 0; i+2 < columnCount(triangles); i += 3 {
+		a, b, c := columnVector(triangles, i), columnVector(triangles, i+1), columnVector(triangles, i+2)
+		volume += a.Dot(b.Cross(c)) / 6
+	}
+	return math.Abs(volume)
+}
+
+// columnCount returns the number of columns of a matrix.
+func columnCount(matrix [][]float64) int {
+	if len(matrix) == 0 {
+		return 0
+	}
+	return len(matrix[0])
+}
+
+// columnVector returns the x, y, and z of column i of a matrix as a vector.
+func columnVector(matrix [][]float64, i int) Vector {
+	return Vector{matrix[0][i], matrix[1][i], matrix[2][i]}
+}
@@ -0,0 +1,364 @@
+// mesh provides the indexed Mesh type along with checks for, and repairs of,
+// common problems in imported or generated meshes.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Mesh is a triangle mesh whose faces index into a shared list of vertices.
+// The corners of each face are listed counterclockwise when seen from the
+// front. Colors is either empty or holds an RGB color for every vertex.
+type Mesh struct {
+	Vertices []Vector
+	Faces    [][3]int
+	Colors   [][]int
+}
+
+// NewMesh creates a mesh from a matrix whose every three consecutive columns
+// are the corners of a triangle. Every corner becomes its own vertex. It
+// returns the new mesh.
+func NewMesh(triangles [][]float64) Mesh {
+	var m Mesh
+	for i := 0; i+2 < columnCount(triangles); i += 3 {
+		first := len(m.Vertices)
+		for j := 0; j < 3; j++ {
+			m.Vertices = append(m.Vertices, columnVector(triangles, i+j))
+		}
+		m.Faces = append(m.Faces, [3]int{first, first + 1, first + 2})
+	}
+	return m
+}
+
+// Triangles returns a 4xN matrix whose every three consecutive columns are
+// the corners of a face of the mesh. Faces with a corner outside the list of
+// vertices are left out.
+func (m Mesh) Triangles() [][]float64 {
+	triangles := NewEdgeMatrix()
+	for _, f := range m.Faces {
+		if !m.hasFace(f) {
+			continue
+		}
+		for _, v := range f {
+			p := m.Vertices[v]
+			triangles.AddPoint(p[0], p[1], p[2])
+		}
+	}
+	return triangles
+}
+
+// checkShape returns an error if a face of the mesh has a corner outside the
+// list of vertices, or if the mesh has colors but not an RGB color for every
+// vertex.
+func (m Mesh) checkShape() error {
+	for i, f := range m.Faces {
+		if !m.hasFace(f) {
+			return fmt.Errorf("face %d has corners %v, but the mesh has %d vertices", i, f, len(m.Vertices))
+		}
+	}
+
+	if len(m.Colors) == 0 {
+		return nil
+	} else if len(m.Colors) != len(m.Vertices) {
+		return fmt.Errorf("mesh has %d colors for %d vertices", len(m.Colors), len(m.Vertices))
+	}
+	for i, c := range m.Colors {
+		if len(c) < 3 {
+			return fmt.Errorf("color %d has %d channels, want 3", i, len(c))
+		}
+	}
+	return nil
+}
+
+// hasFace reports whether every corner of face f is a vertex of the mesh.
+func (m Mesh) hasFace(f [3]int) bool {
+	for _, v := range f {
+		if v < 0 || v >= len(m.Vertices) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasColors reports whether the mesh has a color for every vertex.
+func (m Mesh) hasColors() bool {
+	return len(m.Colors) > 0 && len(m.Colors) == len(m.Vertices)
+}
+
+// MeshReport lists the problems found in a mesh by Validate.
+type MeshReport struct {
+	// NonManifoldEdges are the edges, as pairs of vertex indices, shared by
+	// more than two faces.
+	NonManifoldEdges [][2]int
+	// DuplicateVertices are the indices of vertices at the same position as
+	// an earlier vertex.
+	DuplicateVertices []int
+	// DegenerateFaces are the indices of faces with no area or with a corner
+	// outside the list of vertices.
+	DegenerateFaces []int
+	// FlippedFaces are the indices of faces wound against the neighbors they
+	// share edges with.
+	FlippedFaces []int
+}
+
+// OK reports whether the report found no problems.
+func (r MeshReport) OK() bool {
+	return len(r.NonManifoldEdges) == 0 && len(r.DuplicateVertices) == 0 &&
+		len(r.DegenerateFaces) == 0 && len(r.FlippedFaces) == 0
+}
+
+// String summarizes the report.
+func (r MeshReport) String() string {
+	return fmt.Sprintf("%d non-manifold edges, %d duplicate vertices, %d degenerate faces, %d flipped faces",
+		len(r.NonManifoldEdges), len(r.DuplicateVertices), len(r.DegenerateFaces), len(r.FlippedFaces))
+}
+
+// Validate checks the mesh for non-manifold edges, duplicate vertices,
+// degenerate faces, and faces wound inconsistently with their neighbors. It
+// returns a report of every problem found.
+func (m Mesh) Validate() MeshReport {
+	var r MeshReport
+
+	seen := make(map[Vector]bool)
+	for i, v := range m.Vertices {
+		if seen[v] {
+			r.DuplicateVertices = append(r.DuplicateVertices, i)
+		}
+		seen[v] = true
+	}
+
+	for i := range m.Faces {
+		if m.degenerate(i) {
+			r.DegenerateFaces = append(r.DegenerateFaces, i)
+		}
+	}
+
+	for e, faces := range m.edgeFaces() {
+		if len(faces) > 2 {
+			r.NonManifoldEdges = append(r.NonManifoldEdges, e)
+		}
+	}
+
+	_, r.FlippedFaces = m.orient()
+	return r
+}
+
+// Repair returns a copy of the mesh with vertices within epsilon of each other
+// welded, degenerate faces removed, and every face wound consistently with its
+// neighbors. Non-manifold edges are left as they are.
+func (m Mesh) Repair(epsilon float64) Mesh {
+	repaired := m.Weld(epsilon)
+
+	faces := make([][3]int, 0, len(repaired.Faces))
+	for i, f := range repaired.Faces {
+		if !repaired.degenerate(i) {
+			faces = append(faces, f)
+		}
+	}
+	repaired.Faces = faces
+
+	repaired.Faces, _ = repaired.orient()
+	return repaired
+}
+
+// degenerate reports whether face i repeats a vertex, has a corner outside the
+// list of vertices, or has no area.
+func (m Mesh) degenerate(i int) bool {
+	f := m.Faces[i]
+	if !m.hasFace(f) || f[0] == f[1] || f[1] == f[2] || f[0] == f[2] {
+		return true
+	}
+	a, b, c := m.Vertices[f[0]], m.Vertices[f[1]], m.Vertices[f[2]]
+	return b.Sub(a).Cross(c.Sub(a)).Length() == 0
+}
+
+// Weld returns a copy of the mesh in which every vertex within epsilon of an
+// earlier vertex is merged into it, with the faces' indices rebuilt to match.
+// Unused vertices, and faces with a corner outside the list of vertices, are
+// dropped. An epsilon of 0 merges only vertices at exactly the same position.
+func (m Mesh) Weld(epsilon float64) Mesh {
+	var welded Mesh
+	// Exact positions are looked up directly. Otherwise vertices are kept
+	// in a grid of cells epsilon wide, so only neighboring cells are
+	// searched.
+	exact := make(map[Vector]int)
+	grid := make(map[[3]int64][]int)
+	cell := func(v Vector) [3]int64 {
+		return [3]int64{int64(math.Floor(v[0] / epsilon)), int64(math.Floor(v[1] / epsilon)), int64(math.Floor(v[2] / epsilon))}
+	}
+	// find returns the welded vertex v merges into, or -1 if there is none.
+	find := func(v Vector) int {
+		if epsilon <= 0 {
+			if j, ok := exact[v]; ok {
+				return j
+			}
+			return -1
+		}
+		c := cell(v)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, j := range grid[[3]int64{c[0] + dx, c[1] + dy, c[2] + dz}] {
+						if welded.Vertices[j].Sub(v).Length() <= epsilon {
+							return j
+						}
+					}
+				}
+			}
+		}
+		return -1
+	}
+
+	used := make([]bool, len(m.Vertices))
+	for _, f := range m.Faces {
+		if !m.hasFace(f) {
+			continue
+		}
+		for _, v := range f {
+			used[v] = true
+		}
+	}
+
+	remap := make([]int, len(m.Vertices))
+	for i, v := range m.Vertices {
+		if !used[i] {
+			continue
+		}
+
+		if remap[i] = find(v); remap[i] < 0 {
+			remap[i] = len(welded.Vertices)
+			if epsilon <= 0 {
+				exact[v] = remap[i]
+			} else {
+				c := cell(v)
+				grid[c] = append(grid[c], remap[i])
+			}
+			welded.Vertices = append(welded.Vertices, v)
+			if m.hasColors() {
+				welded.Colors = append(welded.Colors, m.Colors[i])
+			}
+		}
+	}
+
+	for _, f := range m.Faces {
+		if m.hasFace(f) {
+			welded.Faces = append(welded.Faces, [3]int{remap[f[0]], remap[f[1]], remap[f[2]]})
+		}
+	}
+	return welded
+}
+
+// compact returns a copy of the mesh without the vertices no face uses, with
+// the faces' indices rebuilt to match. Vertices at the same position are kept
+// apart.
+func (m Mesh) compact() Mesh {
+	used := make([]bool, len(m.Vertices))
+	for _, f := range m.Faces {
+		if !m.hasFace(f) {
+			continue
+		}
+		for _, v := range f {
+			used[v] = true
+		}
+	}
+
+	var compacted Mesh
+	remap := make([]int, len(m.Vertices))
+	for i, v := range m.Vertices {
+		if !used[i] {
+			continue
+		}
+		remap[i] = len(compacted.Vertices)
+		compacted.Vertices = append(compacted.Vertices, v)
+		if m.hasColors() {
+			compacted.Colors = append(compacted.Colors, m.Colors[i])
+		}
+	}
+
+	for _, f := range m.Faces {
+		if m.hasFace(f) {
+			compacted.Faces = append(compacted.Faces, [3]int{remap[f[0]], remap[f[1]], remap[f[2]]})
+		}
+	}
+	return compacted
+}
+
+// edgeFaces maps every edge of the mesh, as a pair of vertex indices with the
+// smaller first, to the faces that use it.
+func (m Mesh) edgeFaces() map[[2]int][]int {
+	edges := make(map[[2]int][]int)
+	for i, f := range m.Faces {
+		for j := 0; j < 3; j++ {
+			e := undirectedEdge(f[j], f[(j+1)%3])
+			edges[e] = append(edges[e], i)
+		}
+	}
+	return edges
+}
+
+// orient winds every face the same way as the first face of its connected
+// piece of the mesh, walking across edges shared by exactly two faces. It
+// returns the reoriented faces and the indices of the faces it flipped.
+func (m Mesh) orient() ([][3]int, []int) {
+	faces := append([][3]int{}, m.Faces...)
+	edges := m.edgeFaces()
+	visited := make([]bool, len(faces))
+	flipped := make([]int, 0)
+
+	for start := range faces {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		queue := []int{start}
+
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+
+			for j := 0; j < 3; j++ {
+				a, b := faces[f][j], faces[f][(j+1)%3]
+				neighbors := edges[undirectedEdge(a, b)]
+				if len(neighbors) != 2 {
+					continue
+				}
+
+				g := neighbors[0]
+				if g == f {
+					g = neighbors[1]
+				}
+				if visited[g] {
+					continue
+				}
+				visited[g] = true
+
+				if hasDirectedEdge(faces[g], a, b) {
+					faces[g][1], faces[g][2] = faces[g][2], faces[g][1]
+					flipped = append(flipped, g)
+				}
+				queue = append(queue, g)
+			}
+		}
+	}
+	return faces, flipped
+}
+
+// undirectedEdge returns the edge between vertices a and b with the smaller
+// index first.
+func undirectedEdge(a, b int) [2]int {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// hasDirectedEdge reports whether face f goes from vertex a straight to b.
+func hasDirectedEdge(f [3]int, a, b int) bool {
+	for j := 0; j < 3; j++ {
+		if f[j] == a && f[(j+1)%3] == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// metadata provides render metadata, such as the frame number and camera, that
+// can be stamped into rendered images and PNG text chunks so that the output
+// of a render farm can be traced back to what produced it.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata describes a rendered frame. An empty Scene, zero Time, or nil
+// Camera is left out when the metadata is stamped.
+type Metadata struct {
+	Scene  string
+	Frame  int
+	Time   time.Time
+	Camera [][]float64
+}
+
+// Stamp selects where metadata is stamped when a frame is saved.
+type Stamp int
+
+const (
+	// StampImage draws the metadata as text in the lower left corner of the
+	// image.
+	StampImage Stamp = 1 << iota
+	// StampPNG stores the metadata in tEXt chunks of a PNG file.
+	StampPNG
+)
+
+// Metadata returns the metadata of the renderer's current frame of a scene,
+// with the current time and the renderer's camera.
+func (r *Renderer) Metadata(scene string, frame int) Metadata {
+	return Metadata{Scene: scene, Frame: frame, Time: time.Now(), Camera: r.Camera}
+}
+
+// Fields returns the metadata as keywords and values in a fixed order. The
+// camera matrix is written one row to a line.
+func (m Metadata) Fields() [][2]string {
+	var fields [][2]string
+	if m.Scene != "" {
+		fields = append(fields, [2]string{"Scene", m.Scene})
+	}
+	fields = append(fields, [2]string{"Frame", strconv.Itoa(m.Frame)})
+	if !m.Time.IsZero() {
+		fields = append(fields, [2]string{"Time", m.Time.Format(time.RFC3339)})
+	}
+	if m.Camera != nil {
+		rows := make([]string, len(m.Camera))
+		for i, row := range m.Camera {
+			values := make([]string, len(row))
+			for j, v := range row {
+				values[j] = strconv.FormatFloat(v, 'g', 4, 64)
+			}
+			rows[i] = strings.Join(values, " ")
+		}
+		fields = append(fields, [2]string{"Camera", strings.Join(rows, "\n")})
+	}
+	return fields
+}
+
+// StampMetadata draws the metadata onto a screen in the built-in bitmap font,
+// one field to a line, in the lower left corner.
+func StampMetadata(screen [][][]int, m Metadata, color []int) {
+	const margin = 2
+
+	var lines []string
+	for _, field := range m.Fields() {
+		indent := strings.Repeat(" ", len(field[0])+2)
+		value := strings.ReplaceAll(field[1], "\n", "\n"+indent)
+		lines = append(lines, field[0]+": "+value)
+	}
+	text := strings.Join(lines, "\n")
+
+	_, height := TextSize(text, 1)
+	DrawText(screen, margin, float64(margin+height-glyphHeight), text, color)
+}
+
+// SaveWithMetadata saves the renderer's filtered screen to filename with the
+// metadata stamped where stamp says, drawing it in the renderer's color. It
+// returns an error if the PNG chunks are asked for but filename is not a PNG
+// file, or if the file cannot be written.
+func (r *Renderer) SaveWithMetadata(filename string, m Metadata, stamp Stamp) error {
+	isPNG := strings.EqualFold(filepath.Ext(filename), ".png")
+	if stamp&StampPNG != 0 && !isPNG {
+		return fmt.Errorf("metadata: %s is not a PNG file", filename)
+	}
+
+	screen := r.Output()
+	if stamp&StampImage != 0 {
+		StampMetadata(screen, m, r.Color)
+	}
+
+	if !isPNG {
+		WriteScreenToExtension(screen, filename)
+		return nil
+	}
+
+	var fields [][2]string
+	if stamp&StampPNG != 0 {
+		fields = m.Fields()
+	}
+	return writePNGText(screenToImage(screen), filename, fields)
+}
+
+// writePNGText encodes img as a PNG into the file named filename with a tEXt
+// chunk for every keyword and value of fields.
+func writePNGText(img image.Image, filename string, fields [][2]string) error {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+
+	// The text chunks go straight after the signature and the IHDR chunk,
+	// which always holds 13 bytes of data.
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	data := encoded.Bytes()
+
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	for _, field := range fields {
+		writePNGChunk(&out, "tEXt", []byte(field[0]+"\x00"+field[1]))
+	}
+	out.Write(data[ihdrEnd:])
+	return os.WriteFile(filename, out.Bytes(), 0644)
+}
+
+// writePNGChunk writes a PNG chunk of the given type and data to buffer.
+func writePNGChunk(buffer *bytes.Buffer, kind string, data []byte) {
+	binary.Write(buffer, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	buffer.WriteString(kind)
+	buffer.Write(data)
+	binary.Write(buffer, binary.BigEndian, crc.Sum32())
+}
@@ -0,0 +1,185 @@
+// obj provides a reader and writer for Wavefront OBJ files, so that meshes
+// made in modeling tools can be drawn alongside generated primitives and
+// generated geometry can be taken into other tools.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadOBJ reads a mesh from the Wavefront OBJ data in r. Vertices come from v
+// records and faces from f records, whose corners may carry texture and
+// normal indices, which are ignored, and may be counted back from the latest
+// vertex with negative indices. Faces of more than three corners are split
+// into a fan of triangles. If every vertex lists an RGB color after its
+// position, from 0 to 1, the mesh gets those colors. Other records are
+// ignored. It returns an error naming the line of the first malformed record.
+func ReadOBJ(r io.Reader) (Mesh, error) {
+	var m Mesh
+	colored := true
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			values, err := parseFloats(fields[1:])
+			if err != nil || len(values) < 3 {
+				return Mesh{}, fmt.Errorf("obj line %d: vertex needs 3 numbers, got %q", line, strings.Join(fields[1:], " "))
+			}
+			m.Vertices = append(m.Vertices, Vector{values[0], values[1], values[2]})
+
+			if len(values) == 6 {
+				m.Colors = append(m.Colors, []int{
+					clampChannel(values[3] * 255),
+					clampChannel(values[4] * 255),
+					clampChannel(values[5] * 255),
+				})
+			} else {
+				colored = false
+			}
+		case "f":
+			if len(fields) < 4 {
+				return Mesh{}, fmt.Errorf("obj line %d: face has %d corners, want at least 3", line, len(fields)-1)
+			}
+
+			corners := make([]int, len(fields)-1)
+			for i, field := range fields[1:] {
+				index, err := objIndex(field, len(m.Vertices))
+				if err != nil {
+					return Mesh{}, fmt.Errorf("obj line %d: %v", line, err)
+				}
+				corners[i] = index
+			}
+			for i := 1; i+1 < len(corners); i++ {
+				m.Faces = append(m.Faces, [3]int{corners[0], corners[i], corners[i+1]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Mesh{}, err
+	}
+
+	if !colored {
+		m.Colors = nil
+	}
+	return m, nil
+}
+
+// objIndex returns the index into the vertices read so far of the vertex a
+// face corner such as "3", "3/1", or "-1//2" refers to. It returns an error if
+// the corner does not refer to one of those vertices.
+func objIndex(corner string, vertices int) (int, error) {
+	text, _, _ := strings.Cut(corner, "/")
+	index, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("bad face corner %q", corner)
+	}
+
+	if index < 0 {
+		index += vertices
+	} else {
+		index--
+	}
+	if index < 0 || index >= vertices {
+		return 0, fmt.Errorf("face corner %q refers to a missing vertex", corner)
+	}
+	return index, nil
+}
+
+// LoadOBJ reads the Wavefront OBJ file named filename. It returns the
+// triangles of its mesh as a polygon matrix, or an error if the file cannot
+// be read or is malformed.
+func LoadOBJ(filename string) (PolygonMatrix, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m, err := ReadOBJ(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return PolygonMatrix(m.Triangles()), nil
+}
+
+// WriteOBJ writes edges and polygons to w as Wavefront OBJ data: a v record
+// for every distinct point, an l record for every edge, and an f record for
+// every triangle. Either matrix may be nil. It returns an error if either
+// matrix is malformed or the data cannot be written.
+func WriteOBJ(w io.Writer, edges EdgeMatrix, polygons PolygonMatrix) error {
+	if edges != nil {
+		if err := edges.Validate(); err != nil {
+			return err
+		}
+	}
+	if polygons != nil {
+		if err := polygons.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Number the distinct points first, so that every v record can be
+	// written ahead of the records that use them.
+	var points []Vector
+	indices := make(map[Vector]int)
+	vertex := func(m [][]float64, i int) int {
+		p := columnVector(m, i)
+		index, ok := indices[p]
+		if !ok {
+			points = append(points, p)
+			index = len(points)
+			indices[p] = index
+		}
+		return index
+	}
+
+	var records []string
+	for i := 0; i+1 < edges.Len(); i += 2 {
+		records = append(records, fmt.Sprintf("l %d %d", vertex(edges, i), vertex(edges, i+1)))
+	}
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		a, b, c := vertex(polygons, i), vertex(polygons, i+1), vertex(polygons, i+2)
+		records = append(records, fmt.Sprintf("f %d %d %d", a, b, c))
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, p := range points {
+		fmt.Fprintf(bw, "v %s %s %s\n", formatOBJ(p[0]), formatOBJ(p[1]), formatOBJ(p[2]))
+	}
+	for _, record := range records {
+		fmt.Fprintln(bw, record)
+	}
+	return bw.Flush()
+}
+
+// formatOBJ formats a coordinate for an OBJ file in as few digits as read it
+// back exactly.
+func formatOBJ(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// SaveOBJ writes edges and polygons to the Wavefront OBJ file named filename,
+// as WriteOBJ does. It returns an error if the file cannot be written.
+func SaveOBJ(filename string, edges EdgeMatrix, polygons PolygonMatrix) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteOBJ(file, edges, polygons); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,305 @@
+// options provides functional options for drawing operations.
+package main
+
+import (
+	"math"
+)
+
+// DrawOption configures a drawing operation such as DrawLine.
+type DrawOption func(*drawOptions)
+
+type drawOptions struct {
+	color    []int
+	width    int
+	step     float64
+	subpixel int
+	aa       bool
+	zbuffer  [][]float64
+	gradient [2][]int
+
+	// from and to are the endpoints of the line being drawn, used to find
+	// the depth and gradient color of each point plotted.
+	from, to [3]float64
+}
+
+// WithColor makes a drawing operation use color instead of the color it was
+// given.
+func WithColor(color []int) DrawOption {
+	return func(o *drawOptions) {
+		o.color = color
+	}
+}
+
+// WithWidth makes a drawing operation draw lines width pixels wide.
+func WithWidth(width int) DrawOption {
+	return func(o *drawOptions) {
+		o.width = width
+	}
+}
+
+// WithStep makes a drawing operation sample a line every step pixels along
+// its length instead of rasterizing it with the midpoint algorithm.
+func WithStep(step float64) DrawOption {
+	return func(o *drawOptions) {
+		o.step = step
+	}
+}
+
+// WithSubpixel makes a drawing operation sample every pixel near a line n by n
+// times and shade it by how much of the line covers it, so that lines at
+// fractional positions are drawn smoothly instead of snapping to whole pixels.
+func WithSubpixel(n int) DrawOption {
+	return func(o *drawOptions) {
+		o.subpixel = n
+	}
+}
+
+// WithAA turns antialiasing on or off for a drawing operation.
+func WithAA(aa bool) DrawOption {
+	return func(o *drawOptions) {
+		o.aa = aa
+	}
+}
+
+// WithGradient makes a drawing operation color lines with a gradient that
+// blends from c0 at their start to c1 at their end instead of a single color.
+func WithGradient(c0, c1 []int) DrawOption {
+	return func(o *drawOptions) {
+		o.gradient = [2][]int{c0, c1}
+	}
+}
+
+// WithZBuffer makes a drawing operation compare the depth of every pixel it
+// draws against zbuffer, skipping pixels behind what was already drawn there
+// and recording the depth of the rest.
+func WithZBuffer(zbuffer [][]float64) DrawOption {
+	return func(o *drawOptions) {
+		o.zbuffer = zbuffer
+	}
+}
+
+// newDrawOptions applies opts on top of the defaults for a drawing operation
+// with the given color. It returns the resulting options.
+func newDrawOptions(color []int, opts ...DrawOption) drawOptions {
+	o := drawOptions{color: color, width: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.width < 1 {
+		o.width = 1
+	}
+	return o
+}
+
+// plot draws a point (x, y) onto a screen as a square brush of the option's
+// width.
+func (o drawOptions) plot(screen [][][]int, x, y float64) {
+	o.blend(screen, x, y, 1)
+}
+
+// blend mixes the option's color into a screen around (x, y) with a square
+// brush of the option's width, weighted by alpha.
+func (o drawOptions) blend(screen [][][]int, x, y, alpha float64) {
+	color := o.colorAt(x, y)
+	offset := float64(o.width-1) / 2
+	for i := 0; i < o.width; i++ {
+		for j := 0; j < o.width; j++ {
+			px, py := x-offset+float64(i), y-offset+float64(j)
+			if !o.depthTest(screen, px, py) {
+				continue
+			} else if alpha >= 1 {
+				plot(screen, px, py, color)
+			} else {
+				blend(screen, px, py, color, alpha)
+			}
+		}
+	}
+}
+
+// depthTest reports whether the point (x, y) of the line being drawn is in
+// front of what the option's z-buffer holds at that pixel, recording its depth
+// if so. Every point passes if there is no z-buffer.
+func (o drawOptions) depthTest(screen [][][]int, x, y float64) bool {
+	if o.zbuffer == nil {
+		return true
+	}
+
+	z := math.Max(o.from[2], o.to[2])
+	if t, ok := o.along(x, y); ok {
+		z = o.from[2] + t*(o.to[2]-o.from[2])
+	}
+	return testDepth(screen, o.zbuffer, x, y, z)
+}
+
+// along returns how far along the line being drawn, from 0 at its start to 1
+// at its end, the point (x, y) lies, and false if the line has no length.
+func (o drawOptions) along(x, y float64) (float64, bool) {
+	dx, dy := o.to[0]-o.from[0], o.to[1]-o.from[1]
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return 0, false
+	}
+	t := ((x-o.from[0])*dx + (y-o.from[1])*dy) / lengthSquared
+	return math.Max(0, math.Min(1, t)), true
+}
+
+// colorAt returns the color of the point (x, y) of the line being drawn: the
+// option's color, or the blend of its gradient there if it has one.
+func (o drawOptions) colorAt(x, y float64) []int {
+	c0, c1 := o.gradient[0], o.gradient[1]
+	if len(c0) < 3 || len(c1) < 3 {
+		return o.color
+	}
+
+	t, _ := o.along(x, y)
+	color := make([]int, 3)
+	for i := range color {
+		color[i] = clampChannel(float64(c0[i]) + t*float64(c1[i]-c0[i]))
+	}
+	return color
+}
+
+// drawLineSampled draws a line from (x0, y0) to (x1, y1) by plotting points
+// every step pixels along it.
+func drawLineSampled(screen [][][]int, x0, y0, x1, y1 float64, o drawOptions) {
+	length := math.Hypot(x1-x0, y1-y0)
+	if length == 0 {
+		o.plot(screen, x0, y0)
+		return
+	}
+
+	for d := 0.0; d <= length; d += o.step {
+		t := d / length
+		o.plot(screen, x0+t*(x1-x0), y0+t*(y1-y0))
+	}
+	o.plot(screen, x1, y1)
+}
+
+// drawLineAA draws an antialiased line from (x0, y0) to (x1, y1) using Xiaolin
+// Wu's algorithm.
+func drawLineAA(screen [][][]int, x0, y0, x1, y1 float64, o drawOptions) {
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0 = y0, x0
+		x1, y1 = y1, x1
+	}
+	if x1 < x0 {
+		x0, x1 = x1, x0
+		y0, y1 = y1, y0
+	}
+
+	gradient := 1.0
+	if dx := x1 - x0; dx != 0 {
+		gradient = (y1 - y0) / dx
+	}
+
+	draw := func(x, y, alpha float64) {
+		if steep {
+			x, y = y, x
+		}
+		o.blend(screen, x, y, alpha)
+	}
+
+	y := y0 + gradient*(math.Round(x0)-x0)
+	for x := math.Round(x0); x <= math.Round(x1); x++ {
+		base := math.Floor(y)
+		frac := y - base
+		draw(x, base, 1-frac)
+		draw(x, base+1, frac)
+		y += gradient
+	}
+}
+
+// drawLineSubpixel draws a line from (x0, y0) to (x1, y1) by shading every
+// pixel near it with the fraction of its sub-pixel samples that fall within
+// half the option's width of the line. Only pixels on the screen are sampled,
+// walking along the line's longer axis and across it only as far as the line
+// reaches.
+func drawLineSubpixel(screen [][][]int, x0, y0, x1, y1 float64, o drawOptions) {
+	height := len(screen)
+	if height == 0 {
+		return
+	}
+	width := len(screen[0])
+
+	radius := math.Max(float64(o.width)/2, 0.5)
+	n := o.subpixel
+	samples := float64(n * n)
+
+	shade := func(px, py float64) {
+		covered := 0
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				sx := px - 0.5 + (float64(i)+0.5)/float64(n)
+				sy := py - 0.5 + (float64(j)+0.5)/float64(n)
+				if distanceToSegment(sx, sy, x0, y0, x1, y1) <= radius {
+					covered++
+				}
+			}
+		}
+
+		if covered > 0 && !o.depthTest(screen, px, py) {
+			return
+		} else if covered == n*n {
+			plot(screen, px, py, o.colorAt(px, py))
+		} else if covered > 0 {
+			blend(screen, px, py, o.colorAt(px, py), float64(covered)/samples)
+		}
+	}
+
+	// Walk along a, the longer axis, and across b, the shorter one.
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	a0, b0, a1, b1 := x0, y0, x1, y1
+	lastA, lastB := width-1, height-1
+	if steep {
+		a0, b0, a1, b1 = y0, x0, y1, x1
+		lastA, lastB = lastB, lastA
+	}
+	if a1 < a0 {
+		a0, b0, a1, b1 = a1, b1, a0, b0
+	}
+	// bAt returns b where the line crosses a, or at the nearer end of the
+	// line if it does not.
+	bAt := func(a float64) float64 {
+		if a1 == a0 {
+			return b0
+		}
+		t := math.Max(0, math.Min(1, (a-a0)/(a1-a0)))
+		return b0 + t*(b1-b0)
+	}
+
+	// Samples lie within half a pixel of their pixel's center, so pixels up
+	// to the radius and half a pixel past the line can be covered.
+	reach := radius + 0.5
+	start := math.Max(math.Floor(a0-reach), 0)
+	end := math.Min(math.Ceil(a1+reach), float64(lastA))
+	for a := start; a <= end; a++ {
+		low, high := bAt(a-reach), bAt(a+reach)
+		if high < low {
+			low, high = high, low
+		}
+		from := math.Max(math.Floor(low-reach), 0)
+		to := math.Min(math.Ceil(high+reach), float64(lastB))
+		for b := from; b <= to; b++ {
+			if steep {
+				shade(b, a)
+			} else {
+				shade(a, b)
+			}
+		}
+	}
+}
+
+// distanceToSegment returns the distance from the point (x, y) to the segment
+// from (x0, y0) to (x1, y1).
+func distanceToSegment(x, y, x0, y0, x1, y1 float64) float64 {
+	dx, dy := x1-x0, y1-y0
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return math.Hypot(x-x0, y-y0)
+	}
+
+	t := ((x-x0)*dx + (y-y0)*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(x-(x0+t*dx), y-(y0+t*dy))
+}
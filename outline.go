@@ -0,0 +1,152 @@
+// outline provides a post pass that draws the outlines of objects where the
+// depths of a z-buffer jump or the surfaces they describe fold, for toon
+// rendering and for making technical renders readable without wireframes.
+package main
+
+import (
+	"math"
+)
+
+// Outline returns a filter that draws lines of color, one pixel wide, along
+// the outlines of the objects drawn into zbuffer. Outlines are drawn wherever
+// an object meets the background, wherever the depths of neighboring pixels
+// differ by more than the fraction depth of the range of depths drawn, and
+// wherever the surface bends by more than crease between neighboring pixels.
+// Surface normals are worked out from the slope of the depths, scaled so that
+// their range is as deep as the screen is wide, so that neither threshold
+// depends on the units of the z-buffer. A crease of 0 draws no creases. Lines
+// fall on the nearer side of each outline. The z-buffer is read when the
+// filter runs. To outline a renderer's own z-buffer, and those of its forks,
+// add OutlinePass to its passes instead.
+func Outline(zbuffer [][]float64, color []int, depth float64, crease Angle) Filter {
+	return func(screen [][][]int) [][][]int {
+		height, width := len(screen), screenWidth(screen)
+		if len(zbuffer) < height {
+			height = len(zbuffer)
+		}
+
+		out := CopyScreen(screen)
+		mark := func(i, j int) {
+			copy(out[i][j], color)
+		}
+
+		depths := scaleDepths(zbuffer, float64(width))
+		normals := depthNormals(depths)
+		maxStep := depth * float64(width)
+		minCos := math.Cos(crease.Radians())
+		for i := 0; i < height; i++ {
+			for j := 0; j < width && j < len(depths[i]); j++ {
+				// Compare each pixel with its neighbors in the next column
+				// and row, so that every pair is compared once.
+				for _, n := range [][2]int{{i, j + 1}, {i + 1, j}} {
+					ni, nj := n[0], n[1]
+					if ni >= height || nj >= width || nj >= len(depths[ni]) {
+						continue
+					}
+
+					z, nz := depths[i][j], depths[ni][nj]
+					near := [2]int{i, j}
+					if nz > z {
+						near = n
+					}
+					switch covered, ncovered := !math.IsInf(z, -1), !math.IsInf(nz, -1); {
+					case covered != ncovered:
+					case !covered:
+						continue
+					case math.Abs(z-nz) > maxStep:
+					case crease.Radians() > 0 && normals[i][j].Dot(normals[ni][nj]) < minCos:
+					default:
+						continue
+					}
+					mark(near[0], near[1])
+				}
+			}
+		}
+		return out
+	}
+}
+
+// OutlinePass returns a pass that outlines the objects drawn into the z-buffer
+// of the renderer it is applied to, as Outline does.
+func OutlinePass(color []int, depth float64, crease Angle) Pass {
+	return func(r *Renderer) Filter {
+		return Outline(r.ZBuffer, color, depth, crease)
+	}
+}
+
+// scaleDepths returns a copy of a z-buffer with its covered depths moved and
+// scaled to run from 0 to size, leaving uncovered pixels at negative infinity.
+func scaleDepths(zbuffer [][]float64, size float64) [][]float64 {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, row := range zbuffer {
+		for _, z := range row {
+			if !math.IsInf(z, -1) {
+				min, max = math.Min(min, z), math.Max(max, z)
+			}
+		}
+	}
+
+	scale := 1.0
+	if max > min {
+		scale = size / (max - min)
+	}
+	depths := make([][]float64, len(zbuffer))
+	for i, row := range zbuffer {
+		depths[i] = make([]float64, len(row))
+		for j, z := range row {
+			depths[i][j] = z
+			if !math.IsInf(z, -1) {
+				depths[i][j] = (z - min) * scale
+			}
+		}
+	}
+	return depths
+}
+
+// depthNormals works out the unit normal of the surface at every covered
+// pixel of a z-buffer from the slope of its depths, in units of pixels. Each
+// slope is taken toward whichever neighbor it is gentler toward, so that the
+// normals on either side of a fold stay those of their own side. Uncovered
+// pixels get zero normals.
+func depthNormals(zbuffer [][]float64) [][]Vector {
+	normals := make([][]Vector, len(zbuffer))
+	at := func(i, j int) (float64, bool) {
+		if i < 0 || i >= len(zbuffer) || j < 0 || j >= len(zbuffer[i]) || math.IsInf(zbuffer[i][j], -1) {
+			return 0, false
+		}
+		return zbuffer[i][j], true
+	}
+	slope := func(z, before float64, hasBefore bool, after float64, hasAfter bool) float64 {
+		switch {
+		case hasBefore && hasAfter:
+			if math.Abs(after-z) < math.Abs(z-before) {
+				return after - z
+			}
+			return z - before
+		case hasAfter:
+			return after - z
+		case hasBefore:
+			return z - before
+		}
+		return 0
+	}
+
+	for i := range zbuffer {
+		normals[i] = make([]Vector, len(zbuffer[i]))
+		for j := range zbuffer[i] {
+			z, ok := at(i, j)
+			if !ok {
+				continue
+			}
+			left, hasLeft := at(i, j-1)
+			right, hasRight := at(i, j+1)
+			up, hasUp := at(i-1, j)
+			down, hasDown := at(i+1, j)
+
+			dx := slope(z, left, hasLeft, right, hasRight)
+			dy := slope(z, up, hasUp, down, hasDown)
+			normals[i][j] = Vector{-dx, -dy, 1}.Normalize()
+		}
+	}
+	return normals
+}
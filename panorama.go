@@ -0,0 +1,66 @@
+// panorama provides rendering of 360 degree equirectangular panoramas, which
+// can be viewed in VR and 360 degree players.
+package main
+
+import (
+	"math"
+)
+
+// RenderPanorama draws an edge matrix as seen in every direction from center
+// onto a new equirectangular screen of size width by height, using the
+// renderer's color. Longitude runs across the screen with the negative z
+// direction in the middle, and latitude runs up it. The renderer's
+// environment, if set, is drawn behind the edges. It returns the new screen.
+func (r *Renderer) RenderPanorama(edges EdgeMatrix, center Vector, width, height int) ([][][]int, error) {
+	if err := edges.checkShape(); err != nil {
+		return nil, err
+	}
+
+	screen := NewScreen(width, height)
+	if r.Environment != nil {
+		for i := range screen {
+			for j := range screen[i] {
+				lon := ((float64(j)+0.5)/float64(width) - 0.5) * 2 * math.Pi
+				lat := (0.5 - (float64(i)+0.5)/float64(height)) * math.Pi
+				d := Vector{math.Cos(lat) * math.Sin(lon), math.Sin(lat), -math.Cos(lat) * math.Cos(lon)}
+				copy(screen[i][j], r.Environment.Sample(d))
+			}
+		}
+	}
+
+	for i := 0; i < edges.Len()-1; i += 2 {
+		a := columnVector(edges, i).Sub(center)
+		b := columnVector(edges, i+1).Sub(center)
+
+		// Sample the edge finely enough that each piece spans about a pixel
+		// of longitude, since straight edges become curves in the panorama.
+		angle := math.Acos(math.Max(-1, math.Min(1, a.Normalize().Dot(b.Normalize()))))
+		samples := int(math.Ceil(angle/(2*math.Pi)*float64(width))) + 1
+
+		px, py, ok := equirectangular(a, width, height)
+		for s := 1; s <= samples; s++ {
+			t := float64(s) / float64(samples)
+			x, y, next := equirectangular(a.Add(b.Sub(a).Scale(t)), width, height)
+			if ok && next && math.Abs(x-px) < float64(width)/2 {
+				DrawLine(screen, px, py, 0, x, y, 0, r.Color)
+			}
+			px, py, ok = x, y, next
+		}
+	}
+	return screen, nil
+}
+
+// equirectangular maps the direction d to the point of a width by height
+// equirectangular screen it appears at. It returns false for the zero vector.
+func equirectangular(d Vector, width, height int) (x, y float64, ok bool) {
+	length := d.Length()
+	if length == 0 {
+		return 0, 0, false
+	}
+
+	lon := math.Atan2(d[0], -d[2])
+	lat := math.Asin(d[1] / length)
+	x = (lon/(2*math.Pi) + 0.5) * float64(width)
+	y = (lat/math.Pi + 0.5) * float64(height)
+	return x, y, true
+}
@@ -3,11 +3,36 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// Command is a single command of a script along with its arguments and the
+// line of the script it starts on.
+type Command struct {
+	Name string
+	Args []string
+	Line int
+}
+
+// immediateCommands are the commands that take no line of arguments.
+var immediateCommands = map[string]bool{
+	"ident":   true,
+	"display": true,
+	"clear":   true,
+	"apply":   true,
+	"quit":    true,
+	"draw":    true,
+	"show":    true,
+	"color":   true,
+	"push":    true,
+	"pop":     true,
+}
+
 /* ParseFile goes through the file named filename and performs all of the
    actions listed in that file.
 
@@ -23,136 +48,381 @@ The file follows the following format:
 	    takes 8 arguments (x0, y0, x1, y1, x2, y2, x3, y3)
     line: add a line to the edge matrix -
 	    takes 6 arguemnts (x0, y0, z0, x1, y1, z1)
+    box: add the 12 edges of an axis-aligned rectangular prism to the edge
+      matrix -
+	    takes 6 arguments (x, y, z, width, height, depth) where (x, y, z) is the
+	    upper-left front corner
 	  ident: set the transform matrix to the identity matrix -
 	  scale: create a scale matrix, then multiply the transform matrix by the
       scale matrix -
 	    takes 3 arguments (sx, sy, sz)
-    translate: create a translation matrix, then multiply the transform matrix
+    move: create a translation matrix, then multiply the transform matrix
       by the translation matrix -
 	    takes 3 arguments (tx, ty, tz)
     rotate: create an rotation matrix, then  multiply the transform matrix by
       the rotation matrix -
 	    takes 2 arguments (axis, theta) axis should be x y or z
     apply: apply the current transformation matrix to the edge  matrix
+    push: push a copy of the current transformation matrix onto the stack
+    pop: restore the transformation matrix from before the matching push
+    width: set how many pixels wide lines are drawn -
+      takes 1 argument (width)
 	  display: draw the lines of the edge matrix to the screen display  the screen
 	  save: draw the lines of the edge matrix to the screen save the screen to a
        file -
 	    takes 1 argument (file name)
+    frames: make the script an animation, run once per frame -
+      takes 1 argument (number of frames)
+    basename: name the files animation frames are saved to -
+      takes 1 argument (name, to which the frame number and .png are added)
+    vary: move a knob steadily between two values over a range of frames -
+      takes 5 arguments (knob, first frame, last frame, first value, last value)
+      and optionally a 6th (linear, ease-in, ease-out, ease-in-out, cubic, or
+      bounce, how the knob moves, linear by default)
+    move, scale, and rotate may take the name of a knob after their numbers,
+      which multiplies them by the knob's value in each frame
 	  quit: end parsing
 */
-func ParseFile(filename string,
-	transform [][]float64,
-	edges [][]float64,
-	screen [][][]int) {
+func ParseFile(filename string, r *Renderer) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
 
-	file, err := os.Open(filename)
+	commands, err := ParseScript(data)
 	if err != nil {
 		panic(err)
 	}
 
-	defer file.Close()
+	if err := RunScript(commands, r); err != nil {
+		panic(err)
+	}
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
+// ParseScript parses the text of a script into its commands without running
+// any of them. Blank lines and lines starting with '#' are skipped. It returns
+// an error if a command is missing its line of arguments.
+func ParseScript(data []byte) ([]Command, error) {
+	commands := make([]Command, 0)
 
-		// Immediate operations (no arguments)
-		if line == "ident" {
-			MakeIdentity(transform)
-			continue
-		} else if line == "display" {
-			ClearScreen(screen)
-			DrawLines(edges, screen)
-			DisplayScreen(screen)
-			continue
-		} else if line == "clear" {
-			edges = make([][]float64, 4)
-			continue
-		} else if line == "apply" {
-			MultiplyMatrices(&transform, &edges)
-			continue
-		} else if line == "quit" {
-			return
-		} else if line == "draw" {
-			DrawLines(edges, screen)
-			continue
-		} else if line == "show" {
-			DisplayScreen(screen)
-			continue
-		} else if strings.Contains(line, "color") {
-			SetColor(strings.Fields(line)[1])
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0][0] == '#' {
 			continue
 		}
 
-		if len(line) == 0 || line[0] == '#' {
-			continue
+		command := Command{Name: fields[0], Args: fields[1:], Line: lineNumber}
+		if !immediateCommands[command.Name] {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("line %d: %s is missing its arguments", lineNumber, command.Name)
+			}
+			lineNumber++
+			command.Args = strings.Fields(scanner.Text())
 		}
 
-		scanner.Scan()
-
-		// Non-immediate operations (has arguments)
-		params := scanner.Text()
-
-		if line == "save" {
-			WriteScreenToExtension(screen, params)
-		} else if line == "line" {
-			AddEdge(edges, FloatParams(params)...)
-		} else if line == "circle" {
-			AddCircle(edges, FloatParams(params)...)
-		} else if line == "sphere" {
-			AddSphere(edges, FloatParams(params)...)
-		} else if line == "box" {
-			AddBox(edges, FloatParams(params)...)
-		} else if line == "torus" {
-			AddTorus(edges, FloatParams(params)...)
-		} else if line == "hermite" || line == "bezier" {
-			p := FloatParams(params)
-			AddCurve(edges, p[0], p[1], p[2], p[3], p[4], p[5], p[6], p[7], 0.001, line)
-		} else {
-			var stepTransform [][]float64
-
-			if line == "move" {
-				stepTransform = MakeTranslationMatrix(FloatParams(params)...)
-			} else if line == "scale" {
-				stepTransform = MakeDilationMatrix(FloatParams(params)...)
-			} else if line == "rotate" {
-				args := strings.Fields(params)
-				numDegrees, err := strconv.ParseFloat(args[1], 64)
-				if err != nil {
-					panic(err)
-				}
-
-				switch args[0] {
-				case "x":
-					stepTransform = MakeRotX(numDegrees)
-				case "y":
-					stepTransform = MakeRotY(numDegrees)
-				case "z":
-					stepTransform = MakeRotZ(numDegrees)
-				}
-			}
+		commands = append(commands, command)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-			if len(transform) == 0 {
-				transform = stepTransform
+	return commands, nil
+}
+
+// RunScript performs the commands of a script with a renderer. It stops at a
+// quit command. It returns an error describing the first command that could
+// not be performed.
+func RunScript(commands []Command, r *Renderer) error {
+	return RunScriptContext(context.Background(), commands, r)
+}
+
+// RunScriptContext is like RunScript but stops between commands, and while
+// drawing, when ctx is done, returning ctx's error.
+//
+// A script with a frames command is an animation. It is run in two passes:
+// the first reads its frames, basename, and vary commands, and the second
+// runs the rest of the script once per frame, starting each frame from a
+// cleared screen and an identity transform, and saves each frame as the PNG
+// file named by its basename and frame number. A quit ends the frame it is
+// in.
+func RunScriptContext(ctx context.Context, commands []Command, r *Renderer) error {
+	anim, err := parseAnimation(commands)
+	if err != nil {
+		return err
+	}
+	if anim.frames == 0 {
+		progress := newProgressTracker(len(commands), r.Progress)
+		return runCommands(ctx, commands, r, nil, progress)
+	}
+
+	progress := newProgressTracker(anim.frames*len(commands), r.Progress)
+	for frame := 0; frame < anim.frames; frame++ {
+		r.Clear()
+		r.Stack = NewStack()
+
+		if err := runCommands(ctx, commands, r, anim.knobs.Frame(frame), progress); err != nil {
+			return fmt.Errorf("frame %d: %v", frame, err)
+		}
+		r.Save(fmt.Sprintf("%s-%03d.png", anim.basename, frame))
+	}
+	return nil
+}
+
+// runCommands performs the commands of a script with a renderer and the
+// values of its knobs, stopping at a quit command.
+func runCommands(ctx context.Context, commands []Command, r *Renderer, knobs map[string]float64, progress *progressTracker) error {
+	edges := NewEdgeMatrix()
+	for i, c := range commands {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if c.Name == "quit" {
+			// The commands after a quit count as done, so that progress
+			// still reaches its total.
+			progress.advance(len(commands) - i)
+			return nil
+		}
+
+		logf(LevelDebug, "running command", Field{"line", c.Line}, Field{"command", c.Name})
+		if err := runCommand(ctx, c, r, &edges, knobs); err != nil {
+			return fmt.Errorf("line %d: %s: %v", c.Line, c.Name, err)
+		}
+		progress.step()
+	}
+
+	return nil
+}
+
+// animation is what the frames, basename, and vary commands of a script say
+// about the animation it draws. A script without a frames command has no
+// frames.
+type animation struct {
+	frames   int
+	basename string
+	knobs    *KnobTable
+}
+
+// parseAnimation reads the frames, basename, and vary commands of a script
+// into its animation and the animation's knob table. The basename defaults to
+// "frame". It returns an error if any of them is malformed, or if the script
+// varies knobs outside of its frames.
+func parseAnimation(commands []Command) (animation, error) {
+	anim := animation{basename: "frame"}
+	var varies []Command
+	for _, c := range commands {
+		var err error
+		switch c.Name {
+		case "frames":
+			if len(c.Args) != 1 {
+				err = fmt.Errorf("got %d arguments, want 1", len(c.Args))
+			} else if anim.frames, err = strconv.Atoi(c.Args[0]); err == nil && anim.frames < 1 {
+				err = fmt.Errorf("%d frames is not a positive number", anim.frames)
+			}
+		case "basename":
+			if len(c.Args) != 1 {
+				err = fmt.Errorf("got %d arguments, want 1", len(c.Args))
 			} else {
-				MultiplyMatrices(&stepTransform, &transform)
+				anim.basename = c.Args[0]
 			}
+		case "vary":
+			varies = append(varies, c)
+		}
+		if err != nil {
+			return animation{}, fmt.Errorf("line %d: %s: %v", c.Line, c.Name, err)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	// Knobs can only be varied once the number of frames is known, and the
+	// frames command may come after them.
+	anim.knobs = NewKnobTable(anim.frames)
+	for _, c := range varies {
+		if err := parseVary(anim.knobs, c.Args); err != nil {
+			return animation{}, fmt.Errorf("line %d: %s: %v", c.Line, c.Name, err)
+		}
+	}
+	return anim, nil
+}
+
+// parseVary parses the arguments of a vary command, a knob name, the first
+// and last frames, the knob's values at them, and optionally the name of an
+// easing, and varies the knob in a knob table. It returns an error if they
+// are malformed or the frames are not a range of the table's frames.
+func parseVary(knobs *KnobTable, args []string) error {
+	if len(args) != 5 && len(args) != 6 {
+		return fmt.Errorf("got %d arguments, want 5 or 6", len(args))
+	}
+
+	ease := Linear
+	if len(args) == 6 {
+		var ok bool
+		if ease, ok = LookupEasing(args[5]); !ok {
+			return fmt.Errorf("unknown easing %q", args[5])
+		}
+	}
+
+	p, err := parseFloats(args[1:5])
+	if err != nil {
+		return err
+	}
+	first, last := int(p[0]), int(p[1])
+	if float64(first) != p[0] || float64(last) != p[1] {
+		return fmt.Errorf("frames %g to %g are not whole frames", p[0], p[1])
+	}
+	return knobs.VaryEased(args[0], first, last, p[2], p[3], ease)
+}
+
+// knobParams parses the arguments of a transform that takes n numbers and
+// optionally the name of a knob after them, which all of the numbers are
+// multiplied by. It returns an error if the arguments are malformed or name a
+// knob without a value.
+func knobParams(args []string, n int, knobs map[string]float64) ([]float64, error) {
+	if len(args) != n+1 {
+		return parseFloats(args)
+	}
+
+	p, err := parseFloats(args[:n])
+	if err != nil {
+		return nil, err
+	}
+	k, ok := knobs[args[n]]
+	if !ok {
+		return nil, fmt.Errorf("knob %q has no value", args[n])
+	}
+	for i := range p {
+		p[i] *= k
+	}
+	return p, nil
+}
+
+// runCommand performs a single command with a renderer, the script's edge
+// matrix, and the values of its knobs.
+func runCommand(ctx context.Context, c Command, r *Renderer, edges *EdgeMatrix, knobs map[string]float64) error {
+	switch c.Name {
+	case "frames", "basename", "vary":
+		// These were read before the script started running.
+	case "ident":
+		MakeIdentity(r.Top())
+	case "display":
+		r.Clear()
+		if err := r.DrawLinesContext(ctx, *edges); err != nil {
+			return err
+		}
+		r.Display()
+	case "clear":
+		*edges = NewEdgeMatrix()
+	case "apply":
+		return r.Apply(edges)
+	case "push":
+		r.Push()
+	case "pop":
+		r.Pop()
+	case "draw":
+		return r.DrawLinesContext(ctx, *edges)
+	case "show":
+		r.Display()
+	case "color":
+		if len(c.Args) < 1 {
+			return fmt.Errorf("missing color name")
+		}
+		r.SetColor(c.Args[0])
+	case "width":
+		if len(c.Args) != 1 {
+			return fmt.Errorf("got %d arguments, want 1", len(c.Args))
+		}
+		width, err := strconv.Atoi(c.Args[0])
+		if err != nil {
+			return err
+		} else if width < 1 {
+			return fmt.Errorf("width %d is less than 1 pixel", width)
+		}
+		r.LineWidth = width
+	case "save":
+		if len(c.Args) < 1 {
+			return fmt.Errorf("missing file name")
+		}
+		r.Save(c.Args[0])
+	case "rotate":
+		if len(c.Args) < 2 {
+			return fmt.Errorf("got %d arguments, want 2", len(c.Args))
+		}
+		p, err := knobParams(c.Args[1:], 1, knobs)
+		if err != nil {
+			return err
+		}
+		theta := Degrees(p[0])
+
+		switch c.Args[0] {
+		case "x":
+			return r.Transform(MakeRotX(theta))
+		case "y":
+			return r.Transform(MakeRotY(theta))
+		case "z":
+			return r.Transform(MakeRotZ(theta))
+		default:
+			return fmt.Errorf("unknown axis %q", c.Args[0])
+		}
+	case "move", "scale":
+		p, err := knobParams(c.Args, 3, knobs)
+		if err != nil {
+			return err
+		}
+		return runPrimitive(c.Name, p, r, *edges)
+	default:
+		p, err := parseFloats(c.Args)
+		if err != nil {
+			return err
+		}
+		return runPrimitive(c.Name, p, r, *edges)
+	}
+
+	return nil
+}
+
+// runPrimitive performs a command whose arguments are all numbers, which is
+// either a transform or a registered primitive.
+func runPrimitive(name string, p []float64, r *Renderer, edges EdgeMatrix) error {
+	switch name {
+	case "move", "scale":
+		if err := checkParams(name, p, 3); err != nil {
+			return err
+		}
+		if name == "move" {
+			return r.Transform(MakeTranslationMatrix(p...))
+		}
+		return r.Transform(MakeDilationMatrix(p...))
+	default:
+		f, ok := LookupPrimitive(name)
+		if !ok {
+			return fmt.Errorf("unknown command")
+		}
+		return f(edges, p...)
+	}
+}
+
+// FloatParams parses the space-separated numbers in text. It returns them as a
+// slice.
+func FloatParams(text string) []float64 {
+	args, err := parseFloats(strings.Fields(text))
+	if err != nil {
 		panic(err)
 	}
+	return args
 }
 
-func FloatParams(text string) (args []float64) {
-	args = []float64{}
-	for _, v := range strings.Fields(text) {
+// parseFloats parses every string in fields as a number. It returns an error
+// for the first one that is not a number.
+func parseFloats(fields []string) ([]float64, error) {
+	args := make([]float64, 0, len(fields))
+	for _, v := range fields {
 		floated, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		args = append(args, floated)
 	}
-	return
+	return args, nil
 }
@@ -0,0 +1,42 @@
+// pick provides screen-space picking, which maps a pixel back to the geometry
+// drawn there.
+package main
+
+import (
+	"math"
+)
+
+// PickEdge finds the edge of an edge matrix nearest to the point (x, y),
+// measured in the edge matrix's x and y coordinates. Edges farther than
+// maxDistance are ignored. It returns the index of the edge's first column and
+// false if no edge is close enough.
+func PickEdge(edges EdgeMatrix, x, y, maxDistance float64) (int, bool) {
+	if edges.checkShape() != nil {
+		return 0, false
+	}
+
+	best, bestDistance := 0, math.Inf(1)
+	for i := 0; i < edges.Len()-1; i += 2 {
+		p0, p1 := edges.Column(i), edges.Column(i+1)
+		d := distanceToSegment(x, y, p0[0], p0[1], p1[0], p1[1])
+		if d <= maxDistance && d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+
+	return best, !math.IsInf(bestDistance, 1)
+}
+
+// Pick finds the edge of an edge matrix drawn nearest to the pixel at column
+// col and row row of the renderer's screen, as seen through its camera. Edges
+// more than 3 pixels away are ignored. It returns the index of the edge's first
+// column and false if no edge is close enough.
+func (r *Renderer) Pick(edges EdgeMatrix, col, row int) (int, bool) {
+	view, err := r.view(edges)
+	if err != nil {
+		return 0, false
+	}
+
+	x, y := float64(col), float64(len(r.Screen)-row-1)
+	return PickEdge(view, x, y, 3)
+}
@@ -0,0 +1,69 @@
+// pointcloud provides point clouds, such as LiDAR scans or photogrammetry
+// data, drawn as squares or discs whose size falls off with depth.
+package main
+
+import "math"
+
+// PointCloud is a set of points drawn as screen-space squares, or discs if
+// Round is set, Size pixels across. Colors is either empty or holds a color
+// for every point; points without one use the renderer's current color.
+// Attenuation shrinks points behind the nearest one, dividing their size by
+// 1 + Attenuation*d for a point d units behind it, so that depth reads
+// clearly.
+type PointCloud struct {
+	Points      []Vector
+	Colors      [][]int
+	Size        float64
+	Attenuation float64
+	Round       bool
+}
+
+// DrawPointCloud draws a point cloud onto the renderer's target, as seen
+// through its camera. Points are never drawn less than a pixel across.
+func (r *Renderer) DrawPointCloud(c PointCloud) error {
+	points := NewEdgeMatrix()
+	for _, p := range c.Points {
+		points.AddPoint(p[0], p[1], p[2])
+	}
+	view, err := r.view(points)
+	if err != nil {
+		return err
+	}
+
+	// Points that are not drawn, such as those behind the eye, are left out
+	// of the nearest depth.
+	nearest := math.Inf(-1)
+	for i, z := range view[2] {
+		if finite(view[0][i], view[1][i], z) {
+			nearest = math.Max(nearest, z)
+		}
+	}
+
+	for i := range c.Points {
+		x, y, z := view[0][i], view[1][i], view[2][i]
+		if !finite(x, y, z) {
+			continue
+		}
+
+		color := r.Color
+		if i < len(c.Colors) {
+			color = c.Colors[i]
+		}
+
+		size := c.Size
+		if c.Attenuation > 0 {
+			size /= 1 + c.Attenuation*(nearest-z)
+		}
+		radius := math.Max(size, 1) / 2
+
+		for py := math.Ceil(y - radius); py < y+radius; py++ {
+			for px := math.Ceil(x - radius); px < x+radius; px++ {
+				if c.Round && math.Hypot(px-x, py-y) > radius {
+					continue
+				}
+				r.Target.Plot(px, py, z, color)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// polygons provides the PolygonMatrix type, which stores solid objects as
+// triangles, and functions for building and drawing polygon matrices.
+package main
+
+import "fmt"
+
+// PolygonMatrix is a 4xN matrix whose columns are homogeneous points
+// (x, y, z, 1). Every three consecutive columns form a triangle.
+type PolygonMatrix [][]float64
+
+// NewPolygonMatrix creates an empty polygon matrix. It returns the new polygon
+// matrix.
+func NewPolygonMatrix() PolygonMatrix {
+	return make(PolygonMatrix, 4)
+}
+
+// Len returns the number of points in the polygon matrix.
+func (m PolygonMatrix) Len() int {
+	return EdgeMatrix(m).Len()
+}
+
+// Column returns the point stored in column i of the polygon matrix.
+func (m PolygonMatrix) Column(i int) []float64 {
+	return ExtractColumn(m, i)
+}
+
+// Validate checks that the polygon matrix has 4 rows of equal length and a
+// number of points divisible by 3. It returns an error describing the first
+// problem found.
+func (m PolygonMatrix) Validate() error {
+	if err := EdgeMatrix(m).checkShape(); err != nil {
+		return err
+	}
+	if len(m[0])%3 != 0 {
+		return fmt.Errorf("polygon matrix has %d points, want a multiple of 3", len(m[0]))
+	}
+	return nil
+}
+
+// AddPolygon adds a triangle (three points) to the polygon matrix. It returns
+// an error if params does not hold 9 finite numbers
+// (x0, y0, z0, x1, y1, z1, x2, y2, z2).
+func (m PolygonMatrix) AddPolygon(params ...float64) error {
+	if err := checkParams("polygon", params, 9); err != nil {
+		return err
+	}
+
+	for i := 0; i < 9; i += 3 {
+		EdgeMatrix(m).AddPoint(params[i], params[i+1], params[i+2])
+	}
+	return nil
+}
+
+// Edges returns an edge matrix holding the three edges of every triangle of
+// the polygon matrix. It returns an error if the polygon matrix is malformed.
+func (m PolygonMatrix) Edges() (EdgeMatrix, error) {
+	if err := EdgeMatrix(m).checkShape(); err != nil {
+		return nil, err
+	}
+
+	edges := NewEdgeMatrix()
+	for i := 0; i+2 < m.Len(); i += 3 {
+		p0, p1, p2 := m.Column(i), m.Column(i+1), m.Column(i+2)
+		edges.AddPoint(p0[0], p0[1], p0[2])
+		edges.AddPoint(p1[0], p1[1], p1[2])
+		edges.AddPoint(p1[0], p1[1], p1[2])
+		edges.AddPoint(p2[0], p2[1], p2[2])
+		edges.AddPoint(p2[0], p2[1], p2[2])
+		edges.AddPoint(p0[0], p0[1], p0[2])
+	}
+	return edges, nil
+}
+
+// DrawPolygons draws the outline of every triangle of a polygon matrix onto a
+// screen with the given color. The options are applied to every line. It
+// returns an error if the polygon matrix is malformed.
+func DrawPolygons(polygons PolygonMatrix, screen [][][]int, color []int, opts ...DrawOption) error {
+	edges, err := polygons.Edges()
+	if err != nil {
+		return err
+	}
+	return DrawLines(edges, screen, color, opts...)
+}
+
+// DrawPolygons draws the outline of every triangle of a polygon matrix onto
+// the renderer's target with its current color, as seen through its camera.
+// Triangles facing away are skipped if the renderer culls back faces. It
+// returns an error if the polygon matrix is malformed.
+func (r *Renderer) DrawPolygons(polygons PolygonMatrix, opts ...DrawOption) error {
+	view, err := r.viewPolygons(polygons)
+	if err != nil {
+		return err
+	}
+
+	edges, err := view.Edges()
+	if err != nil {
+		return err
+	}
+	return RasterizeLines(edges, r.Target, r.Color, r.lineOptions(opts)...)
+}
+
+// FillPolygons fills every triangle of a polygon matrix onto the renderer's
+// target with its current color, plus any light its surfaces give off, which
+// is also kept for Bloom, as seen through its camera. Triangles facing
+// away are skipped if the renderer culls back faces, filled with its cap color
+// if it caps clipped cuts, or filled with its back color if it is double
+// sided. It returns an error if the polygon matrix is malformed.
+func (r *Renderer) FillPolygons(polygons PolygonMatrix) error {
+	view, err := r.viewPolygons(polygons)
+	if err != nil {
+		return err
+	}
+	if r.clipCap() == nil && (!r.DoubleSided || r.BackColor == nil) && r.Emissive == nil {
+		return RasterizeTriangles(view, r.Target, r.Color)
+	}
+
+	for i := 0; i+2 < view.Len(); i += 3 {
+		if !r.fillCap(view, i) {
+			color, _ := r.side(view, i)
+			p0, p1, p2 := view.Column(i), view.Column(i+1), view.Column(i+2)
+			r.drawGlow(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+			r.Target.FillTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], r.emitting(color))
+		}
+	}
+	return nil
+}
+
+// viewPolygons clips a polygon matrix by the renderer's clipping planes and
+// applies its camera to a copy, leaving out the triangles it culls. It returns
+// the copy.
+func (r *Renderer) viewPolygons(polygons PolygonMatrix) (PolygonMatrix, error) {
+	view, err := r.view(EdgeMatrix(r.clipPolygons(polygons)))
+	if err != nil || !r.CullBackfaces {
+		return PolygonMatrix(view), err
+	}
+
+	visible := NewPolygonMatrix()
+	for i := 0; i+2 < view.Len(); i += 3 {
+		if !r.culled(view, i) {
+			for row := range visible {
+				visible[row] = append(visible[row], view[row][i:i+3]...)
+			}
+		}
+	}
+	return visible, nil
+}
+
+// culled reports whether the renderer skips the triangle whose corners are
+// columns i, i+1, and i+2 of a matrix already seen through its camera. Only
+// back faces are skipped, and only if the renderer culls them, is not double
+// sided, and does not need them to cap clipped cuts.
+func (r *Renderer) culled(view [][]float64, i int) bool {
+	return r.CullBackfaces && !r.DoubleSided && r.clipCap() == nil && backFacing(view, i)
+}
+
+// side returns the color the renderer draws the triangle whose corners are
+// columns i, i+1, and i+2 of a matrix already seen through its camera in, and
+// whether its normal is flipped for lighting, which it is for the back faces
+// of double-sided renderers.
+func (r *Renderer) side(view [][]float64, i int) (color []int, flipped bool) {
+	if !r.DoubleSided || !backFacing(view, i) {
+		return r.Color, false
+	} else if r.BackColor != nil {
+		return r.BackColor, true
+	}
+	return r.Color, true
+}
+
+// backFacing reports whether the triangle whose corners are columns i, i+1,
+// and i+2 of a matrix already seen through a camera faces away from it. A
+// triangle faces away when its corners run clockwise on screen, so that its
+// normal points away from the viewer looking down the z axis.
+func backFacing(view [][]float64, i int) bool {
+	return SurfaceNormal(view, i)[2] <= 0
+}
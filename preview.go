@@ -0,0 +1,338 @@
+// preview provides an interactive preview of a scene, redrawn as mouse
+// dragging and scrolling orbit, pan, and zoom its camera and as keys tweak
+// the parameters of the scene, for use as a model viewer. Hotkeys save
+// screenshots and recordings of a session.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// orbitDegreesPerPixel is how far dragging the mouse one pixel turns an orbit.
+const orbitDegreesPerPixel = 0.5
+
+// zoomPerStep is the factor one step of the scroll wheel scales an orbit's
+// distance by.
+const zoomPerStep = 0.9
+
+// recordingFrameDelay is how long each frame of a preview's recording is
+// shown for.
+const recordingFrameDelay = 100 * time.Millisecond
+
+// Orbit is a camera circling Target at Distance, turned Yaw about the y axis
+// from the positive z axis and tilted Pitch above the xz plane, with a
+// vertical field of view of FOV.
+type Orbit struct {
+	Target     Vector
+	Distance   float64
+	Yaw, Pitch Angle
+	FOV        Angle
+}
+
+// Camera returns the camera of the orbit, with up along the y axis.
+func (o Orbit) Camera() Camera {
+	yaw, pitch := o.Yaw.Radians(), o.Pitch.Radians()
+	offset := Vector{
+		math.Cos(pitch) * math.Sin(yaw),
+		math.Sin(pitch),
+		math.Cos(pitch) * math.Cos(yaw),
+	}
+	return Camera{
+		Eye:    o.Target.Add(offset.Scale(o.Distance)),
+		Target: o.Target,
+		Up:     Vector{0, 1, 0},
+		FOV:    o.FOV,
+	}
+}
+
+// Rotate turns the orbit as dragging the mouse dx pixels right and dy pixels
+// up does, keeping it short of looking straight up or down.
+func (o *Orbit) Rotate(dx, dy float64) {
+	o.Yaw = o.Yaw.Add(Degrees(-dx * orbitDegreesPerPixel))
+	pitch := o.Pitch.Degrees() - dy*orbitDegreesPerPixel
+	o.Pitch = Degrees(math.Max(-89, math.Min(89, pitch)))
+}
+
+// Pan moves the orbit's target so that the scene follows the mouse as it is
+// dragged dx pixels right and dy pixels up on a screen height pixels tall.
+func (o *Orbit) Pan(dx, dy float64, height int) {
+	c := o.Camera()
+	forward := c.Target.Sub(c.Eye).Normalize()
+	right := forward.Cross(c.Up).Normalize()
+	up := right.Cross(forward)
+
+	// Parallel views show the scene at its own scale; perspective views
+	// show a slice of it as tall as the field of view is at the target.
+	perPixel := 1.0
+	if fov := o.FOV.Radians(); fov > 0 && height > 0 {
+		perPixel = 2 * o.Distance * math.Tan(fov/2) / float64(height)
+	}
+	o.Target = o.Target.Sub(right.Scale(dx * perPixel)).Sub(up.Scale(dy * perPixel))
+}
+
+// Zoom moves the orbit in toward its target by steps of the scroll wheel, or
+// out for negative steps.
+func (o *Orbit) Zoom(steps float64) {
+	o.Distance *= math.Pow(zoomPerStep, steps)
+}
+
+// MouseButton is a mouse button held while dragging in a preview.
+type MouseButton int
+
+const (
+	// LeftButton drags orbit the camera.
+	LeftButton MouseButton = iota
+	// MiddleButton drags pan the camera.
+	MiddleButton
+	// RightButton drags zoom the camera.
+	RightButton
+)
+
+// Key is a key pressed in a preview: the character it types, or one of the
+// keys below for keys that type none.
+type Key rune
+
+// The arrow keys are given codes in Unicode's private use area, where they
+// cannot be mistaken for typed characters.
+const (
+	KeyUp Key = 0xF700 + iota
+	KeyDown
+	KeyLeft
+	KeyRight
+)
+
+// Preview redraws a scene through an orbiting camera whenever the camera is
+// moved or a knob is turned. Knobs are the knobs of Knobs in its frame Frame,
+// and keys turn them by their Steps, or by 1 for knobs without one.
+// Windowing backends report mouse and key input to the preview and show the
+// frames it presents. While the preview has knobs, their values are drawn in
+// the upper left of every frame, with the selected one marked, and while it
+// is recording, REC is drawn in the upper right; neither appears in
+// screenshots or recordings.
+type Preview struct {
+	Renderer *Renderer
+	Orbit    Orbit
+	Knobs    *KnobTable
+	Frame    int
+	Steps    map[string]float64
+	// Draw draws the scene onto a cleared renderer.
+	Draw func(r *Renderer) error
+	// Present shows a finished frame. A nil Present displays the frame with
+	// DisplayScreen.
+	Present func(screen [][][]int)
+	// CaptureDir is the directory screenshots and recordings are saved in.
+	// An empty CaptureDir saves them in the current directory.
+	CaptureDir string
+
+	selected  int
+	frame     [][][]int
+	recording *GIFAnimation
+}
+
+// NewPreview creates a preview of the scene draw draws, seen by a perspective
+// camera looking at the origin from distance units up the z axis, with a knob
+// table of one frame and no knobs. It returns the new preview.
+func NewPreview(r *Renderer, distance float64, draw func(r *Renderer) error) *Preview {
+	return &Preview{
+		Renderer: r,
+		Orbit:    Orbit{Distance: distance, FOV: Degrees(45)},
+		Knobs:    NewKnobTable(1),
+		Draw:     draw,
+	}
+}
+
+// NewScriptPreview creates a preview like NewPreview does of the scene a
+// script draws, whose knobs are the knobs of the script's animation, so that
+// turning them changes what the script draws. Each refresh runs the script's
+// commands once with the knobs' values in the preview's frame, starting from
+// an identity transform; commands that display or save screens do so as they
+// run. It returns an error if the script's animation is malformed.
+func NewScriptPreview(r *Renderer, distance float64, commands []Command) (*Preview, error) {
+	anim, err := parseAnimation(commands)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewPreview(r, distance, nil)
+	if anim.frames > 0 {
+		p.Knobs = anim.knobs
+	}
+	p.Draw = func(r *Renderer) error {
+		r.Stack = NewStack()
+		progress := newProgressTracker(len(commands), r.Progress)
+		return runCommands(context.Background(), commands, r, p.Knobs.Frame(p.Frame), progress)
+	}
+	return p, nil
+}
+
+// Refresh draws the scene through the preview's camera and presents it. It
+// returns an error if the camera cannot be placed or drawing fails.
+func (p *Preview) Refresh() error {
+	r := p.Renderer
+	if err := r.SetCamera(p.Orbit.Camera()); err != nil {
+		return err
+	}
+
+	r.Clear()
+	if err := p.Draw(r); err != nil {
+		return err
+	}
+
+	p.frame = r.Output()
+	if p.recording != nil {
+		p.recording.AddFrame(p.frame)
+	}
+	p.present()
+	return nil
+}
+
+// present presents the preview's latest frame with its overlays drawn on.
+func (p *Preview) present() {
+	screen := p.frame
+	if len(p.Knobs.Names()) > 0 || p.recording != nil {
+		screen = CopyScreen(p.frame)
+		p.drawOverlay(screen)
+	}
+
+	if p.Present != nil {
+		p.Present(screen)
+	} else {
+		DisplayScreen(screen)
+	}
+}
+
+// drawOverlay draws the values of the preview's knobs and whether it is
+// recording onto a screen.
+func (p *Preview) drawOverlay(screen [][][]int) {
+	top := float64(len(screen) - 2 - glyphHeight)
+	if p.recording != nil {
+		width, _ := TextSize("REC", 1)
+		DrawText(screen, float64(screenWidth(screen)-2-width), top, "REC", []int{255, 0, 0})
+	}
+
+	names := p.Knobs.Names()
+	lines := make([]string, len(names))
+	for i, name := range names {
+		mark := " "
+		if i == p.selected {
+			mark = ">"
+		}
+		lines[i] = fmt.Sprintf("%s%s = %g", mark, name, p.Knob(name))
+	}
+	DrawText(screen, 2, top, strings.Join(lines, "\n"), p.Renderer.Color)
+}
+
+// Knob returns the value of the knob named name in the preview's frame, or 0
+// if it has none there.
+func (p *Preview) Knob(name string) float64 {
+	v, _ := p.Knobs.Value(name, p.Frame)
+	return v
+}
+
+// KeyPress handles a key being pressed. The up and down arrows select the
+// previous and next knob, and the right arrow or + and the left arrow or -
+// step the selected knob up and down, refreshing the preview. P saves the
+// latest frame as a PNG screenshot, and R starts recording every frame, or
+// stops and saves the recording as an animated GIF. Screenshots and
+// recordings are numbered so that none is overwritten. Other keys are
+// ignored. It returns an error if the preview cannot be refreshed or a file
+// cannot be saved.
+func (p *Preview) KeyPress(key Key) error {
+	switch key {
+	case 'p', 'P':
+		if p.frame == nil {
+			return nil
+		}
+		return SavePNG(p.frame, p.captureName("screenshot", ".png"))
+	case 'r', 'R':
+		if p.recording == nil {
+			p.recording = NewGIFAnimation(recordingFrameDelay)
+			if p.frame != nil {
+				p.recording.AddFrame(p.frame)
+				p.present()
+			}
+			return nil
+		}
+
+		recording := p.recording
+		p.recording = nil
+		if p.frame != nil {
+			p.present()
+		}
+		if recording.Len() == 0 {
+			return nil
+		}
+		return recording.Save(p.captureName("recording", ".gif"))
+	case KeyUp, KeyDown:
+		knobs := len(p.Knobs.Names())
+		if knobs == 0 {
+			return nil
+		}
+		step := 1
+		if key == KeyUp {
+			step = knobs - 1
+		}
+		p.selected = (p.selected + step) % knobs
+	case KeyRight, '+', '=':
+		p.turn(1)
+	case KeyLeft, '-', '_':
+		p.turn(-1)
+	default:
+		return nil
+	}
+	return p.Refresh()
+}
+
+// captureName returns the name of the first file in the preview's capture
+// directory named prefix, a number, and ext that does not exist yet.
+func (p *Preview) captureName(prefix, ext string) string {
+	for i := 1; ; i++ {
+		name := filepath.Join(p.CaptureDir, fmt.Sprintf("%s-%03d%s", prefix, i, ext))
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// turn steps the selected knob by steps of its step in the preview's frame.
+func (p *Preview) turn(steps float64) {
+	names := p.Knobs.Names()
+	if p.selected >= len(names) {
+		return
+	}
+
+	name := names[p.selected]
+	step, ok := p.Steps[name]
+	if !ok {
+		step = 1
+	}
+	p.Knobs.Set(name, p.Frame, p.Knob(name)+steps*step)
+}
+
+// MouseDrag handles the mouse being dragged dx pixels right and dy pixels up
+// with a button held, orbiting, panning, or zooming by button, and refreshes
+// the preview.
+func (p *Preview) MouseDrag(button MouseButton, dx, dy float64) error {
+	switch button {
+	case LeftButton:
+		p.Orbit.Rotate(dx, dy)
+	case MiddleButton:
+		p.Orbit.Pan(dx, dy, len(p.Renderer.Screen))
+	case RightButton:
+		p.Orbit.Zoom(dy / 10)
+	}
+	return p.Refresh()
+}
+
+// Scroll handles the scroll wheel turning steps notches away from the user,
+// zooming in, and refreshes the preview.
+func (p *Preview) Scroll(steps float64) error {
+	p.Orbit.Zoom(steps)
+	return p.Refresh()
+}
@@ -0,0 +1,121 @@
+// primitives provides a registry of named primitive generators, which are
+// available as script commands.
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PrimitiveFunc adds the geometry of a primitive described by params to an
+// edge matrix. It returns an error if params do not describe a valid
+// primitive.
+type PrimitiveFunc func(m EdgeMatrix, params ...float64) error
+
+// primitives maps the names of primitives to their generators.
+var primitives = struct {
+	sync.RWMutex
+	m map[string]PrimitiveFunc
+}{m: map[string]PrimitiveFunc{
+	"line":     EdgeMatrix.AddEdge,
+	"circle":   EdgeMatrix.AddCircle,
+	"sphere":   EdgeMatrix.AddSphere,
+	"box":      EdgeMatrix.AddBox,
+	"torus":    EdgeMatrix.AddTorus,
+	"cylinder": EdgeMatrix.AddCylinder,
+	"cone":     EdgeMatrix.AddCone,
+	"hermite":  curvePrimitive("hermite", Hermite),
+	"bezier":   curvePrimitive("bezier", Bezier),
+}}
+
+// tessellations caches the points generated for primitives, keyed on their
+// names and parameters, so that shapes drawn again every frame of an animation
+// are only tessellated once. When it holds maxTessellations shapes it is
+// emptied.
+var tessellations = struct {
+	sync.Mutex
+	m map[string]EdgeMatrix
+}{m: map[string]EdgeMatrix{}}
+
+const (
+	maxTessellations = 256
+	// minCachedPoints is the fewest points a primitive must generate for them
+	// to be cached; smaller shapes are cheaper to generate again.
+	minCachedPoints = 64
+)
+
+// RegisterPrimitive makes the primitive generator f available under name,
+// replacing any primitive already registered under it. Scripts can then add
+// the primitive with a name command followed by a line of its parameters.
+func RegisterPrimitive(name string, f PrimitiveFunc) {
+	primitives.Lock()
+	defer primitives.Unlock()
+	primitives.m[name] = f
+
+	tessellations.Lock()
+	defer tessellations.Unlock()
+	tessellations.m = map[string]EdgeMatrix{}
+}
+
+// LookupPrimitive returns the primitive generator registered under name and
+// whether there is one. The generator reuses the points it generated before
+// for the same parameters.
+func LookupPrimitive(name string) (PrimitiveFunc, bool) {
+	primitives.RLock()
+	defer primitives.RUnlock()
+	f, ok := primitives.m[name]
+	if !ok {
+		return nil, false
+	}
+	return cachedPrimitive(name, f), true
+}
+
+// cachedPrimitive returns a generator that adds the points f generates for
+// the primitive name to an edge matrix, generating them only if they are not
+// already cached.
+func cachedPrimitive(name string, f PrimitiveFunc) PrimitiveFunc {
+	return func(m EdgeMatrix, params ...float64) error {
+		key := fmt.Sprint(name, params)
+		tessellations.Lock()
+		points, ok := tessellations.m[key]
+		tessellations.Unlock()
+		if ok {
+			return m.Append(points)
+		}
+
+		points = NewEdgeMatrix()
+		if err := f(points, params...); err != nil {
+			return err
+		}
+		if points.Len() >= minCachedPoints {
+			tessellations.Lock()
+			if len(tessellations.m) >= maxTessellations {
+				tessellations.m = map[string]EdgeMatrix{}
+			}
+			tessellations.m[key] = points
+			tessellations.Unlock()
+		}
+		return m.Append(points)
+	}
+}
+
+// AddPrimitive adds the primitive registered under name to an edge matrix. It
+// returns an error if no primitive is registered under name.
+func (m EdgeMatrix) AddPrimitive(name string, params ...float64) error {
+	f, ok := LookupPrimitive(name)
+	if !ok {
+		return fmt.Errorf("unknown primitive %q", name)
+	}
+	return f(m, params...)
+}
+
+// curvePrimitive returns a primitive generator for curves of curveType, which
+// take 8 parameters (x0, y0, x1, y1, x2, y2, x3, y3).
+func curvePrimitive(name string, curveType CurveType) PrimitiveFunc {
+	return func(m EdgeMatrix, p ...float64) error {
+		if err := checkParams(name, p, 8); err != nil {
+			return err
+		}
+		return m.AddCurve(p[0], p[1], p[2], p[3], p[4], p[5], p[6], p[7], 0.001, curveType)
+	}
+}
@@ -0,0 +1,78 @@
+// probe provides light probes, which light surfaces with the light of an
+// environment arriving from every direction, so that untextured models pick
+// up color from their surroundings.
+package main
+
+import "math"
+
+// probeSamples is the number of directions an environment is sampled in when
+// a light probe is made from it.
+const probeSamples = 4096
+
+// LightProbe is the diffuse light an environment casts on surfaces facing
+// each direction, kept as the spherical harmonics of the environment up to
+// the second band, which is all diffuse light depends on to within a few
+// percent.
+type LightProbe struct {
+	coefficients [9][3]float64
+}
+
+// NewLightProbe makes a light probe of the light of a cube map, sampling it
+// in directions spread evenly over the sphere. It returns the new probe.
+func NewLightProbe(env CubeMap) LightProbe {
+	var p LightProbe
+	weight := 4 * math.Pi / probeSamples
+	golden := math.Pi * (3 - math.Sqrt(5))
+	for i := 0; i < probeSamples; i++ {
+		// Directions on a Fibonacci spiral each cover about the same area.
+		y := 1 - (float64(i)+0.5)*2/probeSamples
+		radius := math.Sqrt(1 - y*y)
+		theta := golden * float64(i)
+		d := Vector{radius * math.Cos(theta), y, radius * math.Sin(theta)}
+
+		color := env.Sample(d)
+		for k, basis := range shBasis(d) {
+			for c := range p.coefficients[k] {
+				p.coefficients[k][c] += float64(color[c]) * basis * weight
+			}
+		}
+	}
+	return p
+}
+
+// Light returns the light reaching a surface facing normal, in the units of
+// the colors of lights, so that an environment of a single color lights every
+// surface with that color.
+func (p LightProbe) Light(normal Vector) [3]float64 {
+	// The cosine falloff of diffuse light scales each band of the
+	// environment by its own factor, over pi for light reflected evenly.
+	bands := [9]float64{1, 2.0 / 3, 2.0 / 3, 2.0 / 3, 0.25, 0.25, 0.25, 0.25, 0.25}
+
+	var light [3]float64
+	for k, basis := range shBasis(normal.Normalize()) {
+		for c := range light {
+			light[c] += bands[k] * p.coefficients[k][c] * basis
+		}
+	}
+	for c := range light {
+		light[c] = math.Max(0, light[c])
+	}
+	return light
+}
+
+// shBasis returns the real spherical harmonics of the first three bands in
+// the unit direction d.
+func shBasis(d Vector) [9]float64 {
+	x, y, z := d[0], d[1], d[2]
+	return [9]float64{
+		0.282095,
+		0.488603 * y,
+		0.488603 * z,
+		0.488603 * x,
+		1.092548 * x * y,
+		1.092548 * y * z,
+		0.315392 * (3*z*z - 1),
+		1.092548 * x * z,
+		0.546274 * (x*x - y*y),
+	}
+}
@@ -0,0 +1,60 @@
+// progress provides progress reports for long renders.
+package main
+
+import (
+	"time"
+)
+
+// Progress reports how much of a render is done and how long the rest is
+// expected to take.
+type Progress struct {
+	Done, Total int
+	Elapsed     time.Duration
+	Remaining   time.Duration
+}
+
+// Percent returns how much of the render is done, from 0 to 100.
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return 100
+	}
+	return 100 * float64(p.Done) / float64(p.Total)
+}
+
+// ProgressFunc receives progress reports.
+type ProgressFunc func(Progress)
+
+// progressTracker reports the progress of a render of a known number of steps
+// and estimates the time remaining from the time taken so far.
+type progressTracker struct {
+	report ProgressFunc
+	start  time.Time
+	done   int
+	total  int
+}
+
+// newProgressTracker creates a tracker for a render of total steps that sends
+// its reports to report, which may be nil. It returns the new tracker.
+func newProgressTracker(total int, report ProgressFunc) *progressTracker {
+	return &progressTracker{report: report, start: time.Now(), total: total}
+}
+
+// step records that another step of the render is done and reports it.
+func (t *progressTracker) step() {
+	t.advance(1)
+}
+
+// advance records that n more steps of the render are done, such as steps
+// that are skipped, and reports them.
+func (t *progressTracker) advance(n int) {
+	t.done += n
+	if t.report == nil {
+		return
+	}
+
+	p := Progress{Done: t.done, Total: t.total, Elapsed: time.Since(t.start)}
+	if t.done > 0 && t.done < t.total {
+		p.Remaining = p.Elapsed / time.Duration(t.done) * time.Duration(t.total-t.done)
+	}
+	t.report(p)
+}
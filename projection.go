@@ -0,0 +1,129 @@
+// projection provides preset parallel projections, such as isometric and
+// cabinet, for technical illustration and game art, and perspective
+// projection.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Projection is a preset parallel projection.
+type Projection int
+
+const (
+	// Orthographic looks straight down the negative z axis.
+	Orthographic Projection = iota
+	// Isometric shows the x, y, and z axes foreshortened equally.
+	Isometric
+	// Dimetric shows the x and z axes foreshortened equally, with the 2:1
+	// pixel slopes common in game art.
+	Dimetric
+	// Cavalier is an oblique projection that draws depth at full length,
+	// receding at 45 degrees.
+	Cavalier
+	// Cabinet is an oblique projection that draws depth at half length,
+	// receding at 45 degrees.
+	Cabinet
+)
+
+// MakeProjectionMatrix creates the 4x4 matrix of a preset projection. It
+// returns the projection matrix.
+func MakeProjectionMatrix(p Projection) [][]float64 {
+	m := NewMatrix()
+	MakeIdentity(m)
+
+	switch p {
+	case Isometric:
+		m = MakeRotY(Degrees(45))
+		tilt := MakeRotX(Radians(math.Asin(1 / math.Sqrt(3))))
+		MultiplyMatrices(&tilt, &m)
+	case Dimetric:
+		m = MakeRotY(Degrees(45))
+		tilt := MakeRotX(Degrees(30))
+		MultiplyMatrices(&tilt, &m)
+	case Cavalier, Cabinet:
+		depth := 1.0
+		if p == Cabinet {
+			depth = 0.5
+		}
+		angle := Degrees(45).Radians()
+		m[0][2] = -depth * math.Cos(angle)
+		m[1][2] = -depth * math.Sin(angle)
+	}
+	return m
+}
+
+// SetProjection points the renderer's camera through a preset projection
+// centered on the middle of its screen.
+func (r *Renderer) SetProjection(p Projection) {
+	r.Camera = r.aboutScreenCenter(MakeProjectionMatrix(p))
+}
+
+// MakePerspective creates the 4x4 matrix of a perspective projection seen
+// from an eye eyeDistance units up the z axis from the origin, looking down
+// the negative z axis. The matrix sets the homogeneous coordinate w of each
+// point to 1 - z/eyeDistance, so that dividing by it shrinks points toward the
+// origin the farther behind the plane z = 0 they are; points on the plane are
+// left where they are. It returns the projection matrix.
+func MakePerspective(eyeDistance float64) [][]float64 {
+	m := NewMatrix()
+	MakeIdentity(m)
+	m[3][2] = -1 / eyeDistance
+	return m
+}
+
+// Project applies the perspective projection of MakePerspective to an edge
+// matrix in place, dividing through by w. It returns an error if the edge
+// matrix is malformed or eyeDistance is not a positive number.
+func Project(m EdgeMatrix, eyeDistance float64) error {
+	if !(eyeDistance > 0) || math.IsInf(eyeDistance, 1) {
+		return fmt.Errorf("project: eye distance %g is not a positive number", eyeDistance)
+	}
+
+	if err := ApplyMatrix(MakePerspective(eyeDistance), m); err != nil {
+		return err
+	}
+	divide(m)
+	return nil
+}
+
+// divide divides the x, y, and z of every point of an edge matrix by its w,
+// finishing a perspective projection. Points at or behind the eye, where w is
+// not positive, become NaN so that lines and triangles touching them are
+// skipped. Points whose w is already 1 are left alone.
+func divide(m EdgeMatrix) {
+	for i := 0; i < m.Len(); i++ {
+		w := m[3][i]
+		if w == 1 {
+			continue
+		}
+
+		for row := 0; row < 3; row++ {
+			if w > 0 {
+				m[row][i] /= w
+			} else {
+				m[row][i] = math.NaN()
+			}
+		}
+		m[3][i] = 1
+	}
+}
+
+// SetPerspective points the renderer's camera through a perspective projection
+// from an eye eyeDistance units in front of the middle of its screen.
+func (r *Renderer) SetPerspective(eyeDistance float64) {
+	r.Camera = r.aboutScreenCenter(MakePerspective(eyeDistance))
+}
+
+// aboutScreenCenter returns the matrix that applies m about the center of the
+// renderer's screen rather than about the origin.
+func (r *Renderer) aboutScreenCenter(m [][]float64) [][]float64 {
+	cx, cy := float64(screenWidth(r.Screen))/2, float64(len(r.Screen))/2
+
+	centered := MakeTranslationMatrix(-cx, -cy, 0)
+	MultiplyMatrices(&m, &centered)
+	back := MakeTranslationMatrix(cx, cy, 0)
+	MultiplyMatrices(&back, &centered)
+	return centered
+}
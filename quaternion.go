@@ -0,0 +1,133 @@
+// quaternion provides unit quaternions for composing and smoothly
+// interpolating rotations without gimbal lock.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quaternion is the quaternion W + Xi + Yj + Zk. Unit quaternions represent
+// rotations.
+type Quaternion struct {
+	W, X, Y, Z float64
+}
+
+// IdentityQuaternion returns the quaternion of no rotation.
+func IdentityQuaternion() Quaternion {
+	return Quaternion{W: 1}
+}
+
+// AxisAngle returns the quaternion of the rotation by theta about axis, in the
+// same direction as MakeRotate. A zero axis gives no rotation.
+func AxisAngle(axis Vector, theta Angle) Quaternion {
+	k := axis.Normalize()
+	if k.Length() == 0 {
+		return IdentityQuaternion()
+	}
+
+	half := theta.Radians() / 2
+	sin := math.Sin(half)
+	return Quaternion{math.Cos(half), k[0] * sin, k[1] * sin, k[2] * sin}
+}
+
+// QuaternionFromMatrix returns the quaternion of the rotation in the upper left
+// 3x3 of a 4x4 matrix. It returns an error if the matrix is not 4x4.
+func QuaternionFromMatrix(m [][]float64) (Quaternion, error) {
+	if _, err := NewTransform(m); err != nil {
+		return Quaternion{}, err
+	}
+
+	// Use the largest of the four components as the divisor to stay
+	// numerically stable.
+	var q Quaternion
+	trace := m[0][0] + m[1][1] + m[2][2]
+	switch {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		q = Quaternion{s / 4, (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = Quaternion{(m[2][1] - m[1][2]) / s, s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = Quaternion{(m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = Quaternion{(m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4}
+	}
+	if !finite(q.W, q.X, q.Y, q.Z) {
+		return Quaternion{}, fmt.Errorf("matrix holds no rotation")
+	}
+	return q.Normalize(), nil
+}
+
+// Mul returns the product q*r, the rotation by r followed by the rotation by
+// q.
+func (q Quaternion) Mul(r Quaternion) Quaternion {
+	return Quaternion{
+		q.W*r.W - q.X*r.X - q.Y*r.Y - q.Z*r.Z,
+		q.W*r.X + q.X*r.W + q.Y*r.Z - q.Z*r.Y,
+		q.W*r.Y - q.X*r.Z + q.Y*r.W + q.Z*r.X,
+		q.W*r.Z + q.X*r.Y - q.Y*r.X + q.Z*r.W,
+	}
+}
+
+// Conjugate returns the conjugate of q, which for a unit quaternion is the
+// inverse rotation.
+func (q Quaternion) Conjugate() Quaternion {
+	return Quaternion{q.W, -q.X, -q.Y, -q.Z}
+}
+
+// Dot returns the dot product of q and r as 4-vectors.
+func (q Quaternion) Dot(r Quaternion) float64 {
+	return q.W*r.W + q.X*r.X + q.Y*r.Y + q.Z*r.Z
+}
+
+// Normalize returns q scaled to unit length. The zero quaternion becomes the
+// identity.
+func (q Quaternion) Normalize() Quaternion {
+	length := math.Sqrt(q.Dot(q))
+	if length == 0 {
+		return IdentityQuaternion()
+	}
+	return Quaternion{q.W / length, q.X / length, q.Y / length, q.Z / length}
+}
+
+// Rotate returns v rotated by the unit quaternion q.
+func (q Quaternion) Rotate(v Vector) Vector {
+	p := q.Mul(Quaternion{0, v[0], v[1], v[2]}).Mul(q.Conjugate())
+	return Vector{p.X, p.Y, p.Z}
+}
+
+// Matrix returns the 4x4 rotation matrix of the unit quaternion q.
+func (q Quaternion) Matrix() [][]float64 {
+	m := NewMatrix()
+	MakeIdentity(m)
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	m[0][0], m[0][1], m[0][2] = 1-2*(y*y+z*z), 2*(x*y-w*z), 2*(x*z+w*y)
+	m[1][0], m[1][1], m[1][2] = 2*(x*y+w*z), 1-2*(x*x+z*z), 2*(y*z-w*x)
+	m[2][0], m[2][1], m[2][2] = 2*(x*z-w*y), 2*(y*z+w*x), 1-2*(x*x+y*y)
+	return m
+}
+
+// Slerp returns the rotation t of the way from a to b, from 0 to 1, turning at
+// a constant rate along the shortest path between them.
+func Slerp(a, b Quaternion, t float64) Quaternion {
+	a, b = a.Normalize(), b.Normalize()
+	cos := a.Dot(b)
+	if cos < 0 {
+		// q and -q are the same rotation; take the shorter way around.
+		b, cos = Quaternion{-b.W, -b.X, -b.Y, -b.Z}, -cos
+	}
+
+	wa, wb := 1-t, t
+	if cos < 0.9995 {
+		theta := math.Acos(cos)
+		sin := math.Sin(theta)
+		wa, wb = math.Sin((1-t)*theta)/sin, math.Sin(t*theta)/sin
+	}
+	return Quaternion{
+		wa*a.W + wb*b.W, wa*a.X + wb*b.X, wa*a.Y + wb*b.Y, wa*a.Z + wb*b.Z,
+	}.Normalize()
+}
@@ -0,0 +1,197 @@
+// rasterizer provides the Rasterizer interface, which lets scenes be drawn to
+// any output target, and its implementation for screens.
+package main
+
+import (
+	"context"
+	"math"
+)
+
+// cancelCheckInterval is how many points are drawn between checks for
+// cancellation.
+const cancelCheckInterval = 1024
+
+// Rasterizer is an output target that points, lines, and triangles can be
+// drawn onto. The z values are the depths of the points drawn, which targets
+// may use to hide what is behind other shapes.
+type Rasterizer interface {
+	Plot(x, y, z float64, color []int)
+	DrawLine(x0, y0, z0, x1, y1, z1 float64, color []int, opts ...DrawOption)
+	FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color []int)
+	ShadeTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, c0, c1, c2 []int)
+	FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color func(w0, w1, w2 float64) []int)
+}
+
+// ScreenRasterizer is a Rasterizer that draws onto a screen. If ZBuffer is not
+// nil, only points nearer than what was already drawn at their pixel are
+// drawn.
+type ScreenRasterizer struct {
+	Screen  [][][]int
+	ZBuffer [][]float64
+}
+
+// Plot draws a point (x, y, z) onto the screen.
+func (s ScreenRasterizer) Plot(x, y, z float64, color []int) {
+	if s.ZBuffer == nil || testDepth(s.Screen, s.ZBuffer, x, y, z) {
+		plot(s.Screen, x, y, color)
+	}
+}
+
+// DrawLine draws a line from (x0, y0, z0) to (x1, y1, z1) onto the screen.
+func (s ScreenRasterizer) DrawLine(x0, y0, z0, x1, y1, z1 float64, color []int, opts ...DrawOption) {
+	if s.ZBuffer != nil {
+		opts = append([]DrawOption{WithZBuffer(s.ZBuffer)}, opts...)
+	}
+	DrawLine(s.Screen, x0, y0, z0, x1, y1, z1, color, opts...)
+}
+
+// FillTriangle fills the triangle with corners (x0, y0, z0), (x1, y1, z1), and
+// (x2, y2, z2) on the screen, one horizontal span of pixels at a time.
+func (s ScreenRasterizer) FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color []int) {
+	s.FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2, func(w0, w1, w2 float64) []int {
+		return color
+	})
+}
+
+// ShadeTriangle fills the triangle with corners (x0, y0, z0), (x1, y1, z1),
+// and (x2, y2, z2) on the screen, blending the corner colors c0, c1, and c2
+// across it. Channels missing from any corner color are left black.
+func (s ScreenRasterizer) ShadeTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, c0, c1, c2 []int) {
+	color := make([]int, 3)
+	s.FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2, func(w0, w1, w2 float64) []int {
+		for i := range color {
+			color[i] = 0
+			if i < len(c0) && i < len(c1) && i < len(c2) {
+				color[i] = clampChannel(w0*float64(c0[i]) + w1*float64(c1[i]) + w2*float64(c2[i]))
+			}
+		}
+		return color
+	})
+}
+
+// FillTriangleFunc fills the triangle with corners (x0, y0, z0), (x1, y1, z1),
+// and (x2, y2, z2) on the screen. Each pixel gets the color returned by color
+// for the barycentric weights of the corners at that pixel, and is depth
+// tested against the z-buffer, if any, at the depth those weights give.
+func (s ScreenRasterizer) FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color func(w0, w1, w2 float64) []int) {
+	if !finite(x0, y0, z0, x1, y1, z1, x2, y2, z2) {
+		logf(LevelWarn, "skipping triangle with non-finite corners")
+		return
+	}
+
+	area := (x1-x0)*(y2-y0) - (x2-x0)*(y1-y0)
+	if area == 0 {
+		return
+	}
+
+	scanTriangle(x0, y0, x1, y1, x2, y2, len(s.Screen), func(y, left, right float64) {
+		fillSpan(s.Screen, y, left, right, func(x float64) []int {
+			w1 := ((x-x0)*(y2-y0) - (x2-x0)*(y-y0)) / area
+			w2 := ((x1-x0)*(y-y0) - (x-x0)*(y1-y0)) / area
+			w0 := 1 - w1 - w2
+			if s.ZBuffer != nil && !testDepth(s.Screen, s.ZBuffer, x, y, w0*z0+w1*z1+w2*z2) {
+				return nil
+			}
+			return color(w0, w1, w2)
+		})
+	})
+}
+
+// scanTriangle calls span for every whole-numbered y from 0 to height-1
+// covered by the triangle with corners (x0, y0), (x1, y1), and (x2, y2), with
+// the left and right x of the triangle at that y. Rows off the screen are
+// never visited, however far the triangle reaches past it.
+func scanTriangle(x0, y0, x1, y1, x2, y2 float64, height int, span func(y, left, right float64)) {
+	// Sort the corners from bottom to top.
+	if y1 < y0 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	if y2 < y0 {
+		x0, y0, x2, y2 = x2, y2, x0, y0
+	}
+	if y2 < y1 {
+		x1, y1, x2, y2 = x2, y2, x1, y1
+	}
+	if y2 == y0 {
+		return
+	}
+
+	top := math.Min(y2, float64(height-1))
+	for y := math.Max(math.Ceil(y0), 0); y <= top; y++ {
+		long := x0 + (y-y0)/(y2-y0)*(x2-x0)
+		var short float64
+		if y < y1 {
+			short = x0 + (y-y0)/(y1-y0)*(x1-x0)
+		} else if y2 > y1 {
+			short = x1 + (y-y1)/(y2-y1)*(x2-x1)
+		} else {
+			short = x1
+		}
+
+		left, right := math.Min(long, short), math.Max(long, short)
+		span(y, left, right)
+	}
+}
+
+// fillSpan fills the pixels of a screen at height y whose x is from left to
+// right, clipped to the screen, by copying color(x) into the pixel at each x.
+// Pixels for which color returns nil are left as they are.
+func fillSpan(screen [][][]int, y, left, right float64, color func(x float64) []int) {
+	height := len(screen)
+	row := height - int(math.Round(y)) - 1
+	if row < 0 || row >= height {
+		return
+	}
+
+	pixels := screen[row]
+	start := clampInt(int(math.Ceil(left)), 0, len(pixels))
+	end := clampInt(int(math.Floor(right))+1, 0, len(pixels))
+	for x := start; x < end; x++ {
+		if c := color(float64(x)); c != nil {
+			copy(pixels[x], c)
+		}
+	}
+}
+
+// RasterizeLines draws an edge matrix onto a rasterizer with the given color.
+// The options are applied to every line. It returns an error if the edge
+// matrix is malformed.
+func RasterizeLines(edges EdgeMatrix, rasterizer Rasterizer, color []int, opts ...DrawOption) error {
+	return RasterizeLinesContext(context.Background(), edges, rasterizer, color, opts...)
+}
+
+// RasterizeLinesContext is like RasterizeLines but stops early when ctx is
+// done, returning ctx's error.
+func RasterizeLinesContext(ctx context.Context, edges EdgeMatrix, rasterizer Rasterizer, color []int, opts ...DrawOption) error {
+	if err := edges.checkShape(); err != nil {
+		return err
+	}
+
+	for i := 0; i < edges.Len()-1; i += 2 {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		point := edges.Column(i)
+		nextPoint := edges.Column(i + 1)
+		rasterizer.DrawLine(point[0], point[1], point[2], nextPoint[0], nextPoint[1], nextPoint[2], color, opts...)
+	}
+	return nil
+}
+
+// RasterizeTriangles fills every triangle of a polygon matrix onto a
+// rasterizer with the given color. It returns an error if the polygon matrix
+// is malformed.
+func RasterizeTriangles(polygons PolygonMatrix, rasterizer Rasterizer, color []int) error {
+	if err := EdgeMatrix(polygons).checkShape(); err != nil {
+		return err
+	}
+
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		p0, p1, p2 := polygons.Column(i), polygons.Column(i+1), polygons.Column(i+2)
+		rasterizer.FillTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], color)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestScanTriangleStaysOnScreen(t *testing.T) {
+	tests := []struct {
+		name                   string
+		x0, y0, x1, y1, x2, y2 float64
+		rows                   int
+	}{
+		{"on screen", 10, 10, 90, 20, 50, 60, 51},
+		{"tall", 0, -1e8, 100, 1e8, 50, 0, 100},
+		// Adding 1 to numbers this large leaves them unchanged.
+		{"huge", 0, -1e17, 100, 1e17, 50, 0, 100},
+		{"below", 0, -300, 100, -200, 50, -100, 0},
+		{"above", 0, 300, 100, 200, 50, 400, 0},
+	}
+
+	for _, test := range tests {
+		rows := 0
+		scanTriangle(test.x0, test.y0, test.x1, test.y1, test.x2, test.y2, 100, func(y, left, right float64) {
+			if y < 0 || y > 99 {
+				t.Fatalf("%s: scanned row %g of a screen 100 high", test.name, y)
+			}
+			rows++
+		})
+		if rows != test.rows {
+			t.Errorf("%s: scanned %d rows, want %d", test.name, rows, test.rows)
+		}
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 float64
+		want           [4]float64
+		ok             bool
+	}{
+		{"inside", 1, 2, 8, 9, [4]float64{1, 2, 8, 9}, true},
+		{"across", -10, 5, 20, 5, [4]float64{0, 5, 10, 5}, true},
+		{"corner to corner", -10, -10, 20, 20, [4]float64{0, 0, 10, 10}, true},
+		{"outside", -10, 15, 20, 15, [4]float64{}, false},
+		{"missing a corner", -5, 8, 8, 20, [4]float64{}, false},
+	}
+
+	for _, test := range tests {
+		x0, y0, x1, y1, ok := clipLine(test.x0, test.y0, test.x1, test.y1, 0, 0, 10, 10)
+		if ok != test.ok {
+			t.Errorf("%s: clipped = %v, want %v", test.name, ok, test.ok)
+		} else if ok && !closeTo([]float64{x0, y0, x1, y1}, test.want[:]) {
+			t.Errorf("%s: clipped to %v, want %v", test.name, []float64{x0, y0, x1, y1}, test.want)
+		}
+	}
+}
+
+func TestDrawLineHugeCoordinates(t *testing.T) {
+	for _, opts := range [][]DrawOption{nil, {WithWidth(3)}, {WithAA(true)}, {WithStep(0.5)}, {WithSubpixel(4)}} {
+		screen := NewScreen(100, 100)
+		DrawLine(screen, -1e15, 50, 0, 1e15, 50, 0, []int{255, 0, 0}, opts...)
+		DrawLine(screen, 1e15, 1e15, 0, 2e15, -1e15, 0, []int{255, 0, 0}, opts...)
+
+		if got := screen[49][50]; got[0] != 255 || got[1] == 255 {
+			t.Errorf("%d options: middle of the line is %v, want red", len(opts), got)
+		}
+	}
+}
+
+func TestSubpixelLineGradient(t *testing.T) {
+	screen := NewScreen(100, 10)
+	DrawLineGradient(screen, 0, 5, []int{255, 0, 0}, 99, 5, []int{0, 0, 255}, WithSubpixel(2))
+
+	start, end := screen[4][0], screen[4][99]
+	if start[0] != 255 || start[2] != 0 || end[0] != 0 || end[2] != 255 {
+		t.Errorf("line runs from %v to %v, want [255 0 0] to [0 0 255]", start, end)
+	}
+}
+
+func TestBillboardHugeTexture(t *testing.T) {
+	texture := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for x := 0; x < 2; x++ {
+		for y := 0; y < 2; y++ {
+			texture.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	r := NewRendererSize(50, 50)
+	r.DrawBillboard(Vector{25, 25, 0}, Billboard{Width: 1e9, Height: 1e9, Texture: texture})
+	for _, p := range [][2]int{{0, 0}, {49, 49}, {25, 25}} {
+		if got := r.Screen[p[1]][p[0]]; got[0] != 255 || got[1] != 0 {
+			t.Errorf("pixel %v is %v, want red", p, got)
+		}
+	}
+}
+
+func TestDrawSDFPerspective(t *testing.T) {
+	r := NewRenderer()
+	camera := Camera{Eye: Vector{0, 0, 500}, Up: Vector{0, 1, 0}, FOV: Degrees(45)}
+	if err := r.SetCamera(camera); err != nil {
+		t.Fatal(err)
+	}
+	r.Color = []int{255, 0, 0}
+	if err := r.DrawSDF(SphereSDF(Vector{}, 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	width := 0
+	for _, p := range r.Screen[YRES/2] {
+		if p[1] == 0 {
+			width++
+		}
+	}
+	// The sphere fills the angle asin(100 / 500) either side of the eye's
+	// line of sight.
+	focal := YRES / 2 / math.Tan(Degrees(45).Radians()/2)
+	want := 2 * focal * math.Tan(math.Asin(0.2))
+	if math.Abs(float64(width)-want) > 2 {
+		t.Errorf("sphere is %d pixels wide, want about %.0f", width, want)
+	}
+
+	edges := NewEdgeMatrix()
+	edges.AddPoint(0, 0, 100)
+	view, err := r.view(edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.ZBuffer[YRES/2-1][XRES/2]; math.Abs(got-view[2][0]) > 1e-6 {
+		t.Errorf("depth of the front of the sphere is %g, want %g as meshes get", got, view[2][0])
+	}
+}
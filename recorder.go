@@ -0,0 +1,117 @@
+// recorder provides recording of the stream of points, lines, and triangles
+// drawn for a frame, so that the frame can be replayed later at another
+// resolution or onto another output target.
+package main
+
+// Recorder is a Rasterizer that records everything drawn onto it, passing it
+// on to Target as well unless Target is nil. Width and Height are the size of
+// the screen the recorded coordinates are in.
+type Recorder struct {
+	Target        Rasterizer
+	Width, Height int
+
+	calls []func(target Rasterizer, sx, sy float64)
+}
+
+// NewRecorder creates a recorder with nothing recorded that passes what it
+// records on to target, with coordinates in a screen of size width by height.
+// It returns the new recorder.
+func NewRecorder(target Rasterizer, width, height int) *Recorder {
+	return &Recorder{Target: target, Width: width, Height: height}
+}
+
+// Record starts recording everything the renderer draws by putting a recorder
+// in front of its target. Setting the target back to the recorder's Target
+// stops the recording. It returns the recorder.
+func (r *Renderer) Record() *Recorder {
+	rec := NewRecorder(r.Target, screenWidth(r.Screen), len(r.Screen))
+	r.Target = rec
+	return rec
+}
+
+// Len returns the number of points, lines, and triangles recorded.
+func (rec *Recorder) Len() int {
+	return len(rec.calls)
+}
+
+// Reset discards everything recorded, so that the next frame can be recorded.
+func (rec *Recorder) Reset() {
+	rec.calls = nil
+}
+
+// Replay draws everything recorded onto target, in order, with x and y scaled
+// from the recorder's screen to a screen of size width by height. Line widths
+// and plotted points are not scaled.
+func (rec *Recorder) Replay(target Rasterizer, width, height int) {
+	sx, sy := 1.0, 1.0
+	if rec.Width > 0 && rec.Height > 0 {
+		sx, sy = float64(width)/float64(rec.Width), float64(height)/float64(rec.Height)
+	}
+	for _, call := range rec.calls {
+		call(target, sx, sy)
+	}
+}
+
+// Render replays everything recorded onto a new blank screen of size width by
+// height, hiding surfaces with a z-buffer. It returns the screen.
+func (rec *Recorder) Render(width, height int) [][][]int {
+	screen := NewScreen(width, height)
+	rec.Replay(ScreenRasterizer{screen, NewZBuffer(width, height)}, width, height)
+	return screen
+}
+
+// record records a call and makes it on the recorder's target.
+func (rec *Recorder) record(call func(target Rasterizer, sx, sy float64)) {
+	rec.calls = append(rec.calls, call)
+	if rec.Target != nil {
+		call(rec.Target, 1, 1)
+	}
+}
+
+// Plot records a point (x, y, z).
+func (rec *Recorder) Plot(x, y, z float64, color []int) {
+	color = copyColor(color)
+	rec.record(func(t Rasterizer, sx, sy float64) {
+		t.Plot(x*sx, y*sy, z, color)
+	})
+}
+
+// DrawLine records a line from (x0, y0, z0) to (x1, y1, z1).
+func (rec *Recorder) DrawLine(x0, y0, z0, x1, y1, z1 float64, color []int, opts ...DrawOption) {
+	color = copyColor(color)
+	rec.record(func(t Rasterizer, sx, sy float64) {
+		t.DrawLine(x0*sx, y0*sy, z0, x1*sx, y1*sy, z1, color, opts...)
+	})
+}
+
+// FillTriangle records a filled triangle with corners (x0, y0, z0),
+// (x1, y1, z1), and (x2, y2, z2).
+func (rec *Recorder) FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color []int) {
+	color = copyColor(color)
+	rec.record(func(t Rasterizer, sx, sy float64) {
+		t.FillTriangle(x0*sx, y0*sy, z0, x1*sx, y1*sy, z1, x2*sx, y2*sy, z2, color)
+	})
+}
+
+// ShadeTriangle records a triangle shaded between the colors of its corners.
+func (rec *Recorder) ShadeTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, c0, c1, c2 []int) {
+	c0, c1, c2 = copyColor(c0), copyColor(c1), copyColor(c2)
+	rec.record(func(t Rasterizer, sx, sy float64) {
+		t.ShadeTriangle(x0*sx, y0*sy, z0, x1*sx, y1*sy, z1, x2*sx, y2*sy, z2, c0, c1, c2)
+	})
+}
+
+// FillTriangleFunc records a triangle colored by a function of the barycentric
+// weights of its pixels. The function is called again when the triangle is
+// replayed.
+func (rec *Recorder) FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color func(w0, w1, w2 float64) []int) {
+	rec.record(func(t Rasterizer, sx, sy float64) {
+		t.FillTriangleFunc(x0*sx, y0*sy, z0, x1*sx, y1*sy, z1, x2*sx, y2*sy, z2, color)
+	})
+}
+
+// copyColor returns a copy of a color, so that a recording is not changed by
+// later changes to the color it was given.
+func copyColor(color []int) []int {
+	return append([]int(nil), color...)
+}
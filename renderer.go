@@ -0,0 +1,233 @@
+// renderer provides the Renderer type, which holds all of the state needed to
+// draw a scene so that several scenes can be rendered independently.
+package main
+
+import (
+	"context"
+	"image"
+)
+
+// Light is a directional light with a color. Direction points from surfaces
+// toward the light.
+type Light struct {
+	Direction []float64
+	Color     []int
+}
+
+// Pass makes a filter for the renderer it is applied to, so that the filter
+// can read the renderer's own state, such as its z-buffer.
+type Pass func(r *Renderer) Filter
+
+// Renderer holds a screen and its z-buffer along with the current draw color,
+// transform stack, camera, lights, and ambient light used when drawing onto
+// it. Lines are drawn through Target, which draws onto Screen, testing depths
+// against ZBuffer, unless it is replaced. The filters made by Passes, then
+// Filters, are applied to a copy of the screen whenever it is displayed or
+// saved. Clearing fills the screen with
+// Background or, failing that, Environment if either is set. If Progress is
+// set, it receives reports while scripts run. If Capture is set, displaying or
+// saving hands it the filtered screen instead, along with the file name it
+// would have been saved to, or "" when displaying. Lines and polygons are
+// clipped by ClipPlanes before the camera is applied; meshes, points, and
+// billboards are not.
+type Renderer struct {
+	Screen  [][][]int
+	ZBuffer [][]float64
+	Target  Rasterizer
+	Color   []int
+	Stack   Stack
+	Camera  [][]float64
+	Lights  []Light
+	Ambient []int
+	// Emissive, if set, is light that filled and shaded surfaces give off
+	// whatever lights them. It is added to their color, and the Bloom pass makes it
+	// glow.
+	Emissive []int
+	Filters  []Filter
+	Passes   []Pass
+
+	// ClipPlanes cut away what lies behind them. Caps are drawn by filling
+	// the back faces of filled and shaded polygons, so they only look right
+	// on closed surfaces whose triangles run counterclockwise seen from
+	// outside.
+	ClipPlanes []ClipPlane
+
+	// LineWidth, if more than 1, is how many pixels wide the renderer draws
+	// lines and outlines, unless WithWidth says otherwise.
+	LineWidth int
+
+	// CullBackfaces makes filled and outlined polygons facing away from the
+	// camera be skipped.
+	CullBackfaces bool
+	// DoubleSided makes filled and shaded polygons facing away from the
+	// camera be drawn as the other side of the surface instead of culled: lit
+	// as facing the other way, and in BackColor if it is set.
+	DoubleSided bool
+	BackColor   []int
+
+	Background  image.Image
+	Environment *CubeMap
+	// Probe, if set, replaces Ambient in shading with the light it casts on
+	// each surface by the way the surface faces.
+	Probe *LightProbe
+
+	Progress ProgressFunc
+	Capture  func(name string, screen [][][]int)
+
+	glow *glow
+}
+
+// NewRenderer creates a renderer with a blank screen of size XRES by YRES, a
+// black draw color, and identity transform and camera matrices. It returns the
+// new renderer.
+func NewRenderer() *Renderer {
+	return NewRendererSize(XRES, YRES)
+}
+
+// NewRendererSize creates a renderer like NewRenderer does, with a screen of
+// size width by height. It returns the new renderer.
+func NewRendererSize(width, height int) *Renderer {
+	camera := NewMatrix()
+	MakeIdentity(camera)
+
+	screen := NewScreen(width, height)
+	zbuffer := NewZBuffer(width, height)
+	return &Renderer{
+		Screen:  screen,
+		ZBuffer: zbuffer,
+		Target:  ScreenRasterizer{screen, zbuffer},
+		Color:   []int{0, 0, 0},
+		Stack:   NewStack(),
+		Camera:  camera,
+	}
+}
+
+// Top returns the transform at the top of the renderer's stack. If the stack is
+// empty, an identity matrix is pushed first.
+func (r *Renderer) Top() [][]float64 {
+	return r.Stack.Peek()
+}
+
+// Push pushes a copy of the top transform onto the renderer's stack. If the
+// stack is empty, it pushes an identity matrix.
+func (r *Renderer) Push() {
+	r.Stack.Push()
+}
+
+// Pop removes the top transform from the renderer's stack. The last transform
+// is never removed.
+func (r *Renderer) Pop() {
+	r.Stack.Pop()
+}
+
+// Transform multiplies the top transform of the renderer's stack by m, so that
+// m is applied after every transform already on the top, as the transforms of
+// a script are. It returns an error if m is not a 4x4 matrix.
+func (r *Renderer) Transform(m [][]float64) error {
+	return r.Stack.TransformWorld(m)
+}
+
+// Apply applies the top transform of the renderer's stack to an edge matrix. It
+// returns an error if the edge matrix is malformed.
+func (r *Renderer) Apply(edges *EdgeMatrix) error {
+	if err := edges.checkShape(); err != nil {
+		return err
+	}
+
+	top := r.Top()
+	return MultiplyMatrices(&top, (*[][]float64)(edges))
+}
+
+// SetColor sets the color the renderer draws with by name.
+func (r *Renderer) SetColor(color string) {
+	if color == "yellow" {
+		r.Color = []int{255, 255, 0}
+	} else if color == "white" {
+		r.Color = []int{255, 255, 255}
+	} else if color == "black" {
+		r.Color = make([]int, 3)
+	}
+}
+
+// Clear clears the renderer's screen to its background and clears its
+// z-buffer and the light its emissive surfaces gave off.
+func (r *Renderer) Clear() {
+	ClearScreen(r.Screen)
+	if r.Background != nil {
+		DrawBackground(r.Screen, r.Background)
+	} else if r.Environment != nil {
+		DrawEnvironment(r.Screen, *r.Environment)
+	}
+	ClearZBuffer(r.ZBuffer)
+	r.clearGlow()
+}
+
+// DrawLines draws an edge matrix onto the renderer's target with its current
+// color, as seen through its camera. It returns an error if the edge matrix is
+// malformed.
+func (r *Renderer) DrawLines(edges EdgeMatrix, opts ...DrawOption) error {
+	return r.DrawLinesContext(context.Background(), edges, opts...)
+}
+
+// DrawLinesContext is like DrawLines but stops early when ctx is done,
+// returning ctx's error.
+func (r *Renderer) DrawLinesContext(ctx context.Context, edges EdgeMatrix, opts ...DrawOption) error {
+	view, err := r.view(r.clipEdges(edges))
+	if err != nil {
+		return err
+	}
+	return RasterizeLinesContext(ctx, view, r.Target, r.Color, r.lineOptions(opts)...)
+}
+
+// lineOptions returns the options for drawing a line with the renderer: its
+// line width, overridden by opts.
+func (r *Renderer) lineOptions(opts []DrawOption) []DrawOption {
+	if r.LineWidth <= 1 {
+		return opts
+	}
+	return append([]DrawOption{WithWidth(r.LineWidth)}, opts...)
+}
+
+// view applies the renderer's camera to a copy of an edge matrix, dividing
+// through by w when the camera holds a perspective projection. It returns the
+// copy.
+func (r *Renderer) view(edges EdgeMatrix) (EdgeMatrix, error) {
+	if err := edges.checkShape(); err != nil {
+		return nil, err
+	}
+
+	view := edges.Clone()
+	camera := r.Camera
+	if err := MultiplyMatrices(&camera, (*[][]float64)(&view)); err != nil {
+		return nil, err
+	}
+	divide(view)
+	return view, nil
+}
+
+// Display displays the renderer's filtered screen.
+func (r *Renderer) Display() {
+	if r.Capture != nil {
+		r.Capture("", r.Output())
+		return
+	}
+	DisplayScreen(r.Output())
+}
+
+// Save saves the renderer's filtered screen to filename.
+func (r *Renderer) Save(filename string) {
+	if r.Capture != nil {
+		r.Capture(filename, r.Output())
+		return
+	}
+	WriteScreenToExtension(r.Output(), filename)
+}
+
+// Output returns the renderer's screen run through its passes and filters.
+func (r *Renderer) Output() [][][]int {
+	filters := make([]Filter, 0, len(r.Passes)+len(r.Filters))
+	for _, pass := range r.Passes {
+		filters = append(filters, pass(r))
+	}
+	return ApplyFilters(r.Screen, append(filters, r.Filters...)...)
+}
@@ -0,0 +1,174 @@
+// scene provides a scene graph of nodes, each with its own transform,
+// geometry, and render flags, that is drawn with a Renderer.
+package main
+
+// RenderFlags control how a node is drawn.
+type RenderFlags struct {
+	// Visible nodes are drawn. Hidden nodes and their children are skipped.
+	Visible bool
+	// WireframeOnly nodes draw only the outlines of their polygons.
+	WireframeOnly bool
+	// CastShadows nodes cast shadows in RenderShadows.
+	CastShadows bool
+	// DoubleSided nodes draw polygons facing away from the camera, in the
+	// node's BackColor if it has one.
+	DoubleSided bool
+}
+
+// DefaultRenderFlags returns the flags of a visible, shadow-casting node.
+func DefaultRenderFlags() RenderFlags {
+	return RenderFlags{Visible: true, CastShadows: true}
+}
+
+// Node is a node of a scene graph. Its transform places its geometry and its
+// children relative to its parent.
+type Node struct {
+	Name      string
+	Transform Transform
+	Edges     EdgeMatrix
+	Polygons  PolygonMatrix
+	// LOD, if set, is drawn in place of Polygons, at the level for the
+	// distance of the node's origin in front of the camera.
+	LOD *LOD
+	// Color is the color the node's geometry is drawn with. A nil Color uses
+	// the renderer's current color.
+	Color []int
+	// BackColor, if set, is the color the back faces of the polygons of
+	// DoubleSided nodes are drawn with.
+	BackColor []int
+	// Emissive, if set, is light the node's filled polygons give off. A nil
+	// Emissive uses the renderer's.
+	Emissive []int
+	// Billboard, if set, is drawn facing the camera at the node's origin.
+	Billboard *Billboard
+	Flags     RenderFlags
+	Children  []*Node
+}
+
+// NewNode creates a visible node with no geometry and an identity transform.
+// It returns the new node.
+func NewNode(name string) *Node {
+	return &Node{
+		Name:      name,
+		Transform: IdentityTransform(),
+		Edges:     NewEdgeMatrix(),
+		Polygons:  NewPolygonMatrix(),
+		Flags:     DefaultRenderFlags(),
+	}
+}
+
+// AddChild adds child to the node's children. It returns child.
+func (n *Node) AddChild(child *Node) *Node {
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// Find returns the first node named name in the tree rooted at the node,
+// searching depth first, or nil if there is none.
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Walk calls visit for every visible node of the tree rooted at the node, depth
+// first, with the transform from that node to the world. If visit returns an
+// error, walking stops and the error is returned.
+func (n *Node) Walk(visit func(node *Node, world Transform) error) error {
+	return n.walk(IdentityTransform(), visit)
+}
+
+func (n *Node) walk(parent Transform, visit func(node *Node, world Transform) error) error {
+	if !n.Flags.Visible {
+		return nil
+	}
+
+	world := n.Transform.Then(parent)
+	if err := visit(n, world); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if err := child.walk(world, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render draws every visible node of the tree rooted at the node with a
+// renderer. Polygons are filled, or outlined for WireframeOnly nodes, and
+// polygons facing away from the camera are culled if the renderer culls back
+// faces, unless the node is DoubleSided, in which case they are drawn in its
+// BackColor if it has one.
+// Filled polygons give off the node's Emissive light. Nodes with an LOD draw
+// the level for their distance from a perspective camera, or level 0 under
+// parallel cameras, which show things at the same size however far away.
+func (n *Node) Render(r *Renderer) error {
+	color, cull, doubleSided, backColor, emissive := r.Color, r.CullBackfaces, r.DoubleSided, r.BackColor, r.Emissive
+	defer func() {
+		r.Color, r.CullBackfaces, r.DoubleSided, r.BackColor, r.Emissive = color, cull, doubleSided, backColor, emissive
+	}()
+
+	return n.Walk(func(node *Node, world Transform) error {
+		if node.Billboard != nil {
+			m := world.Matrix()
+			r.DrawBillboard(Vector{m[0][3], m[1][3], m[2][3]}, *node.Billboard)
+		}
+
+		r.Color = color
+		if node.Color != nil {
+			r.Color = node.Color
+		}
+
+		if geometry := node.geometry(r, world); geometry.Len() > 0 {
+			polygons, err := world.Apply(EdgeMatrix(geometry))
+			if err != nil {
+				return err
+			}
+
+			r.CullBackfaces = cull && !node.Flags.DoubleSided
+			r.DoubleSided, r.BackColor = node.Flags.DoubleSided, node.BackColor
+			if node.Flags.WireframeOnly {
+				err = r.DrawPolygons(PolygonMatrix(polygons))
+			} else {
+				if node.Emissive != nil {
+					r.Emissive = node.Emissive
+				}
+				err = r.FillPolygons(PolygonMatrix(polygons))
+			}
+			r.CullBackfaces, r.DoubleSided, r.BackColor, r.Emissive = cull, doubleSided, backColor, emissive
+			if err != nil {
+				return err
+			}
+		}
+
+		if node.Edges.Len() == 0 {
+			return nil
+		}
+
+		edges, err := world.Apply(node.Edges)
+		if err != nil {
+			return err
+		}
+		return r.DrawLines(edges)
+	})
+}
+
+// geometry returns the polygons the node draws, before world places them: the
+// level of its LOD for the distance of its origin from the renderer's camera
+// if it has one, or else its Polygons.
+func (n *Node) geometry(r *Renderer, world Transform) PolygonMatrix {
+	if n.LOD == nil || len(n.LOD.Levels) == 0 {
+		return n.Polygons
+	}
+
+	m := world.Matrix()
+	distance, _ := r.viewDistance(Vector{m[0][3], m[1][3], m[2][3]})
+	return PolygonMatrix(n.LOD.Select(distance).Triangles())
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestRenderShadowsCastShadows(t *testing.T) {
+	root := NewNode("root")
+	casting := root.AddChild(NewNode("casting"))
+	casting.Polygons.AddPolygon(200, 200, 100, 300, 200, 100, 250, 300, 100)
+	hidden := root.AddChild(NewNode("hidden"))
+	hidden.Polygons.AddPolygon(200, 20, 100, 300, 20, 100, 250, 120, 100)
+	hidden.Flags.CastShadows = false
+
+	r := NewRenderer()
+	light := Light{Direction: []float64{1, 0, 1}}
+	plane := ShadowPlane{Normal: Vector{0, 0, 1}, Color: []int{9, 9, 9}}
+	if err := root.RenderShadows(r, light, plane); err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(x, y int) []int { return r.Screen[YRES-1-y][x] }
+	// The light shines from up and to the right, so shadows on the plane
+	// z = 0 fall 100 pixels left of polygons at z = 100.
+	if got := at(150, 230); got[0] != 9 {
+		t.Errorf("shadow of the casting node is %v, want [9 9 9]", got)
+	}
+	if got := at(150, 50); got[0] != 255 {
+		t.Errorf("node that casts no shadows left %v, want [255 255 255]", got)
+	}
+
+	if err := root.RenderShadows(r, Light{Direction: []float64{1, 0, 0}}, plane); err == nil {
+		t.Error("light shining along the plane cast shadows")
+	}
+}
@@ -0,0 +1,197 @@
+// sdf provides signed distance fields, which describe shapes by the distance
+// from any point to their surface, and drawing them by raymarching.
+package main
+
+import "math"
+
+// SDF is a signed distance field: it returns the distance from p to the
+// surface of a shape, negative inside the shape. Combined fields may only
+// bound the distance from below, which is all raymarching needs.
+type SDF func(p Vector) float64
+
+const (
+	// sdfMaxSteps is how many steps a ray may take before it is treated as a
+	// miss.
+	sdfMaxSteps = 256
+	// sdfEpsilon is how close a ray must come to a surface to hit it.
+	sdfEpsilon = 0.01
+)
+
+// SphereSDF returns the field of a sphere of center c and radius r.
+func SphereSDF(c Vector, r float64) SDF {
+	return func(p Vector) float64 {
+		return p.Sub(c).Length() - r
+	}
+}
+
+// BoxSDF returns the field of an axis-aligned box of center c that extends
+// half[i] from the center along each axis i.
+func BoxSDF(c, half Vector) SDF {
+	return func(p Vector) float64 {
+		var outside Vector
+		inside := math.Inf(-1)
+		for i := range p {
+			d := math.Abs(p[i]-c[i]) - half[i]
+			outside[i] = math.Max(d, 0)
+			inside = math.Max(inside, d)
+		}
+		return outside.Length() + math.Min(inside, 0)
+	}
+}
+
+// TorusSDF returns the field of a torus of center c around the y axis, like
+// the ones AddTorus draws, whose tube of radius minor runs at distance major
+// from the center.
+func TorusSDF(c Vector, major, minor float64) SDF {
+	return func(p Vector) float64 {
+		d := p.Sub(c)
+		return math.Hypot(math.Hypot(d[0], d[2])-major, d[1]) - minor
+	}
+}
+
+// Union returns the field of the shapes of a and b together.
+func Union(a, b SDF) SDF {
+	return func(p Vector) float64 {
+		return math.Min(a(p), b(p))
+	}
+}
+
+// Intersect returns the field of the space inside both a and b.
+func Intersect(a, b SDF) SDF {
+	return func(p Vector) float64 {
+		return math.Max(a(p), b(p))
+	}
+}
+
+// Subtract returns the field of the shape of a with b cut out of it.
+func Subtract(a, b SDF) SDF {
+	return func(p Vector) float64 {
+		return math.Max(a(p), -b(p))
+	}
+}
+
+// SmoothUnion is like Union but blends the shapes together over a distance of
+// about k where they meet.
+func SmoothUnion(a, b SDF, k float64) SDF {
+	if k <= 0 {
+		return Union(a, b)
+	}
+	return func(p Vector) float64 {
+		da, db := a(p), b(p)
+		h := math.Max(0, math.Min(1, 0.5+0.5*(db-da)/k))
+		return db + h*(da-db) - k*h*(1-h)
+	}
+}
+
+// SmoothSubtract is like Subtract but rounds the edges of the cut over a
+// distance of about k.
+func SmoothSubtract(a, b SDF, k float64) SDF {
+	if k <= 0 {
+		return Subtract(a, b)
+	}
+	return func(p Vector) float64 {
+		da, db := a(p), b(p)
+		h := math.Max(0, math.Min(1, 0.5-0.5*(da+db)/k))
+		return da + h*(-db-da) + k*h*(1-h)
+	}
+}
+
+// Normal returns the direction out of the field's surface at p, estimated from
+// the field's gradient.
+func (f SDF) Normal(p Vector) Vector {
+	const h = sdfEpsilon / 2
+	var n Vector
+	for i := range n {
+		var d Vector
+		d[i] = h
+		n[i] = f(p.Add(d)) - f(p.Sub(d))
+	}
+	return n.Normalize()
+}
+
+// March steps along a ray as far as the field allows each time, up to a
+// distance of far. It returns the distance along the ray to the first surface
+// hit and true, or false if the ray misses.
+func (f SDF) March(ray Ray, far float64) (float64, bool) {
+	direction := ray.Direction.Normalize()
+	t := 0.0
+	for i := 0; i < sdfMaxSteps && t <= far; i++ {
+		d := f(ray.Origin.Add(direction.Scale(t)))
+		if d < sdfEpsilon {
+			return t, true
+		}
+		t += d
+	}
+	return 0, false
+}
+
+// DrawSDF draws the surface of a field onto the renderer's target, as seen
+// through its camera, with its current color lit by its lights. A ray is
+// marched into the scene through every pixel, from near the eye of a
+// perspective camera or well in front of the screen of a parallel one, so the
+// camera must be invertible. Hits are depth tested at the same depths meshes
+// drawn through the camera get. It returns an error if the camera is not
+// invertible.
+func (r *Renderer) DrawSDF(f SDF) error {
+	inverse, err := InvertMatrix(r.Camera)
+	if err != nil {
+		return err
+	}
+
+	// unproject returns the point of the scene that lands on the pixel
+	// (x, y) at screen depth z, or false if it is behind the eye.
+	unproject := func(x, y, z float64) (Vector, bool) {
+		p := []float64{x, y, z, 1}
+		w := dot(inverse[3], p)
+		if !(w > 0) {
+			return Vector{}, false
+		}
+
+		var v Vector
+		for i := range v {
+			v[i] = dot(inverse[i], p) / w
+		}
+		return v, true
+	}
+
+	height := len(r.Screen)
+	width := screenWidth(r.Screen)
+	reach := 4 * float64(width+height)
+	// Rays are marched as far as twice the reach of screen depth measured
+	// in the scene's own units.
+	unit := Vector{-inverse[0][2], -inverse[1][2], -inverse[2][2]}
+	far := 2 * reach * unit.Length()
+
+	for row := 0; row < height; row++ {
+		y := float64(height - row - 1)
+		for col := 0; col < width; col++ {
+			x := float64(col)
+			near, ok := unproject(x, y, reach)
+			if !ok {
+				continue
+			}
+			through, ok := unproject(x, y, 0)
+			if !ok {
+				continue
+			}
+
+			direction := through.Sub(near)
+			if direction.Length() == 0 {
+				continue
+			}
+			t, ok := f.March(Ray{near, direction}, far)
+			if !ok {
+				continue
+			}
+
+			hit := near.Add(direction.Normalize().Scale(t))
+			p := []float64{hit[0], hit[1], hit[2], 1}
+			w := dot(r.Camera[3], p)
+			if !(w > 0) {
+				continue
+			}
+			r.Target.Plot(x, y, dot(r.Camera[2], p)/w, r.lighting(f.Normal(hit), r.Color))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// service provides an HTTP rendering service, so that programs not written in
+// Go can submit scripts to the renderer and download the frames they draw.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JobStatus is the state of a job submitted to a render service.
+type JobStatus string
+
+const (
+	// JobRunning jobs are still being rendered.
+	JobRunning JobStatus = "running"
+	// JobDone jobs have rendered all of their frames.
+	JobDone JobStatus = "done"
+	// JobFailed jobs stopped at an error.
+	JobFailed JobStatus = "failed"
+)
+
+// maxScriptSize is the largest script, in bytes, a render service accepts,
+// maxRunningJobs the most jobs it renders at once, and finishedJobTTL how long
+// it keeps a job after the job finishes.
+const (
+	maxScriptSize  = 10 << 20
+	maxRunningJobs = 4
+	finishedJobTTL = time.Hour
+)
+
+// RenderService is an http.Handler that renders scripts in the background:
+//
+//	POST   /jobs                   submit a script as the request body
+//	GET    /jobs/{id}              get a job's status
+//	GET    /jobs/{id}/frames/{n}   download a job's nth frame as a PNG
+//	DELETE /jobs/{id}              cancel a job and forget it
+//
+// Statuses are JSON objects holding the job's id, status, error if it failed,
+// and the names of its frames. A job's frames are the screens its script shows
+// or saves, in order, with the file names they would have been saved to; a
+// script that does neither has its final screen as its one frame. Nothing is
+// written to the filesystem. Jobs wait while maxRunningJobs others render, and
+// are kept in memory until deleted or, once finished, for finishedJobTTL. A
+// script that panics fails its job rather than the service.
+type RenderService struct {
+	mu      sync.Mutex
+	mux     *http.ServeMux
+	jobs    map[string]*serviceJob
+	next    int
+	running chan struct{}
+}
+
+// serviceJob is a job of a render service. Its fields other than id and
+// cancel are guarded by the service's lock.
+type serviceJob struct {
+	id       string
+	cancel   context.CancelFunc
+	status   JobStatus
+	err      error
+	names    []string
+	frames   [][]byte
+	finished time.Time
+}
+
+// jobStatus is the JSON form of a job's status.
+type jobStatus struct {
+	ID     string    `json:"id"`
+	Status JobStatus `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Frames []string  `json:"frames"`
+}
+
+// NewRenderService creates a render service with no jobs. It returns the new
+// service.
+func NewRenderService() *RenderService {
+	s := &RenderService{
+		mux:     http.NewServeMux(),
+		jobs:    make(map[string]*serviceJob),
+		running: make(chan struct{}, maxRunningJobs),
+	}
+	s.mux.HandleFunc("POST /jobs", s.serveSubmit)
+	s.mux.HandleFunc("GET /jobs/{id}", s.serveStatus)
+	s.mux.HandleFunc("GET /jobs/{id}/frames/{frame}", s.serveFrame)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.serveDelete)
+	return s
+}
+
+// ServeHTTP answers a request to the service.
+func (s *RenderService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(w, req)
+}
+
+// serveSubmit parses the script in the request body and starts rendering it
+// as a new job.
+func (s *RenderService) serveSubmit(w http.ResponseWriter, req *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxScriptSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	commands, err := ParseScript(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.evict(time.Now())
+	s.next++
+	job := &serviceJob{id: strconv.Itoa(s.next), cancel: cancel, status: JobRunning}
+	s.jobs[job.id] = job
+	status := s.statusOf(job)
+	s.mu.Unlock()
+
+	go s.run(ctx, job, commands)
+
+	w.Header().Set("Location", "/jobs/"+job.id)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// run renders a job's script once fewer than maxRunningJobs others are
+// rendering, collecting its frames as PNGs.
+func (s *RenderService) run(ctx context.Context, job *serviceJob, commands []Command) {
+	var names []string
+	var frames [][]byte
+	var err error
+	defer func() {
+		if p := recover(); p != nil {
+			names, frames, err = nil, nil, fmt.Errorf("script panicked: %v", p)
+		}
+		s.finish(job, names, frames, err)
+	}()
+
+	select {
+	case s.running <- struct{}{}:
+		defer func() { <-s.running }()
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
+
+	capture := func(name string, screen [][][]int) {
+		var encoded bytes.Buffer
+		if e := EncodeScreen(&encoded, screen, "png", 0); e != nil && err == nil {
+			err = e
+		}
+		names = append(names, name)
+		frames = append(frames, encoded.Bytes())
+	}
+
+	r := NewRenderer()
+	r.Capture = capture
+	r.Clear()
+	if e := RunScriptContext(ctx, commands, r); e != nil {
+		err = e
+	} else if len(frames) == 0 {
+		capture("", r.Output())
+	}
+}
+
+// finish records the outcome of a job.
+func (s *RenderService) finish(job *serviceJob, names []string, frames [][]byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.names, job.frames, job.err = names, frames, err
+	job.status = JobDone
+	if err != nil {
+		job.status = JobFailed
+	}
+	job.finished = time.Now()
+	job.cancel()
+}
+
+// evict forgets the jobs that finished more than finishedJobTTL before now.
+// The caller must hold the lock.
+func (s *RenderService) evict(now time.Time) {
+	for id, job := range s.jobs {
+		if job.status != JobRunning && now.Sub(job.finished) > finishedJobTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// serveStatus writes a job's status.
+func (s *RenderService) serveStatus(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[req.PathValue("id")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no job %q", req.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.statusOf(job))
+}
+
+// serveFrame writes a frame of a finished job.
+func (s *RenderService) serveFrame(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[req.PathValue("id")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no job %q", req.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	frame, err := strconv.Atoi(req.PathValue("frame"))
+	if err != nil || frame < 0 || frame >= len(job.frames) {
+		http.Error(w, fmt.Sprintf("job %s has no frame %q", job.id, req.PathValue("frame")), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(job.frames[frame])
+}
+
+// serveDelete cancels a job if it is still running and forgets it.
+func (s *RenderService) serveDelete(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[req.PathValue("id")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no job %q", req.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	job.cancel()
+	delete(s.jobs, job.id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// statusOf returns the status of a job. The caller must hold the lock.
+func (s *RenderService) statusOf(job *serviceJob) jobStatus {
+	status := jobStatus{ID: job.id, Status: job.status, Frames: job.names}
+	if status.Frames == nil {
+		status.Frames = []string{}
+	}
+	if job.err != nil {
+		status.Error = job.err.Error()
+	}
+	return status
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
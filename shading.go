@@ -0,0 +1,147 @@
+// shading provides lit drawing of polygon matrices, where the color of each
+// surface comes from how it faces the renderer's lights.
+package main
+
+import "math"
+
+// ShadingMode is the way lighting is computed across a shaded polygon.
+type ShadingMode int
+
+const (
+	// FlatShading lights each triangle once from its surface normal, giving
+	// it a single color.
+	FlatShading ShadingMode = iota
+	// GouraudShading lights each corner from the average normal of the
+	// triangles meeting there and blends the colors across the triangle.
+	GouraudShading
+	// PhongShading blends the corner normals used by GouraudShading across the
+	// triangle and lights every pixel from its own normal.
+	PhongShading
+)
+
+// defaultLight lights scenes with no lights from the direction of the viewer.
+var defaultLight = Light{Direction: []float64{0, 0, 1}, Color: []int{255, 255, 255}}
+
+// DrawShaded fills every triangle of a polygon matrix onto the renderer's
+// target, as seen through its camera, with its current color lit by its lights
+// and ambient light, plus any light its surfaces give off, which is also kept
+// for Bloom. Lighting is computed before the camera is applied. If the
+// renderer has no lights, a white light shines from the viewer. The polygon
+// matrix is clipped by the renderer's clipping planes first. Triangles facing
+// away are skipped if the renderer culls back faces, filled with its cap color
+// if it caps clipped cuts, or lit as the back of the surface if it is double
+// sided. It returns an error if the polygon matrix is malformed.
+func (r *Renderer) DrawShaded(polygons PolygonMatrix, mode ShadingMode) error {
+	polygons = r.clipPolygons(polygons)
+	view, err := r.view(EdgeMatrix(polygons))
+	if err != nil {
+		return err
+	}
+
+	var normals []Vector
+	if mode == GouraudShading || mode == PhongShading {
+		normals = VertexNormals(polygons)
+	}
+
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		if r.culled(view, i) || r.fillCap(view, i) {
+			continue
+		}
+
+		// The back faces of double-sided surfaces are lit as the other
+		// side of the surface, facing the other way.
+		color, flipped := r.side(view, i)
+		facing := 1.0
+		if flipped {
+			facing = -1
+		}
+
+		p0, p1, p2 := view.Column(i), view.Column(i+1), view.Column(i+2)
+		r.drawGlow(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+		switch mode {
+		case GouraudShading:
+			c0 := r.lighting(normals[i].Scale(facing), color)
+			c1 := r.lighting(normals[i+1].Scale(facing), color)
+			c2 := r.lighting(normals[i+2].Scale(facing), color)
+			r.Target.ShadeTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], c0, c1, c2)
+		case PhongShading:
+			n0, n1, n2 := normals[i].Scale(facing), normals[i+1].Scale(facing), normals[i+2].Scale(facing)
+			r.Target.FillTriangleFunc(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], func(w0, w1, w2 float64) []int {
+				return r.lighting(n0.Scale(w0).Add(n1.Scale(w1)).Add(n2.Scale(w2)), color)
+			})
+		default:
+			color := r.lighting(SurfaceNormal(polygons, i).Scale(facing), color)
+			r.Target.FillTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], color)
+		}
+	}
+	return nil
+}
+
+// VertexNormals calculates the normal at every point of a polygon matrix as
+// the average of the normals of the triangles with a corner at the same
+// position, weighted by their areas. It returns the normals in the order of
+// the points.
+func VertexNormals(polygons PolygonMatrix) []Vector {
+	sums := make(map[Vector]Vector)
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		n := SurfaceNormal(polygons, i)
+		for j := i; j < i+3; j++ {
+			p := columnVector(polygons, j)
+			sums[p] = sums[p].Add(n)
+		}
+	}
+
+	normals := make([]Vector, polygons.Len())
+	for i := range normals {
+		normals[i] = sums[columnVector(polygons, i)].Normalize()
+	}
+	return normals
+}
+
+// lighting returns the color of a surface of the given color facing normal,
+// lit by the renderer's ambient light, or the light of its probe if it has
+// one, and by each of its lights by how squarely the surface faces it
+// (Lambert's cosine law), plus the light the renderer's surfaces give off.
+func (r *Renderer) lighting(normal Vector, color []int) []int {
+	n := normal.Normalize()
+	lights := r.Lights
+	if len(lights) == 0 {
+		lights = []Light{defaultLight}
+	}
+
+	light := make([]float64, 3)
+	if r.Probe != nil {
+		ambient := r.Probe.Light(n)
+		copy(light, ambient[:])
+	} else {
+		for i := range light {
+			if i < len(r.Ambient) {
+				light[i] = float64(r.Ambient[i])
+			}
+		}
+	}
+	for _, l := range lights {
+		if len(l.Direction) < 3 || len(l.Color) < 3 {
+			continue
+		}
+
+		d := Vector{l.Direction[0], l.Direction[1], l.Direction[2]}.Normalize()
+		intensity := math.Max(0, n.Dot(d))
+		for i := range light {
+			light[i] += intensity * float64(l.Color[i])
+		}
+	}
+
+	lit := make([]int, len(color))
+	for i := range lit {
+		lit[i] = color[i]
+		if i < len(light) {
+			emitted := 0.0
+			if i < len(r.Emissive) {
+				emitted = float64(r.Emissive[i])
+			}
+			lit[i] = clampChannel(float64(color[i])*light[i]/255 + emitted)
+		}
+	}
+	return lit
+}
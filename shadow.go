@@ -0,0 +1,59 @@
+// shadow provides planar shadows: the polygons of scene nodes flattened onto a
+// plane along a light, to ground objects on a floor without a shadow map.
+package main
+
+import "fmt"
+
+// ShadowPlane is a plane shadows are cast onto, made of the points p where
+// Normal·p is Offset, and the color they are filled with. A nil Color uses
+// the renderer's current color.
+type ShadowPlane struct {
+	Normal Vector
+	Offset float64
+	Color  []int
+}
+
+// RenderShadows fills the shadows that the visible CastShadows nodes of the
+// tree rooted at the node cast onto plane from a directional light, so it is
+// usually called after drawing the plane and before Render. Each polygon is
+// flattened onto the plane along the light's direction, toward which it
+// shines from infinitely far away. It returns an error if the light has no
+// direction or shines along the plane.
+func (n *Node) RenderShadows(r *Renderer, light Light, plane ShadowPlane) error {
+	if len(light.Direction) < 3 {
+		return fmt.Errorf("shadows: light direction %v has fewer than 3 numbers", light.Direction)
+	}
+	d := Vector{light.Direction[0], light.Direction[1], light.Direction[2]}
+	facing := plane.Normal.Dot(d)
+	if facing == 0 {
+		return fmt.Errorf("shadows: light direction %v runs along the plane", d)
+	}
+
+	color, cull, doubleSided, emissive := r.Color, r.CullBackfaces, r.DoubleSided, r.Emissive
+	defer func() {
+		r.Color, r.CullBackfaces, r.DoubleSided, r.Emissive = color, cull, doubleSided, emissive
+	}()
+	if plane.Color != nil {
+		r.Color = plane.Color
+	}
+	// Flattening can turn polygons around, so none are culled.
+	r.CullBackfaces, r.DoubleSided, r.Emissive = false, false, nil
+
+	return n.Walk(func(node *Node, world Transform) error {
+		geometry := node.geometry(r, world)
+		if !node.Flags.CastShadows || geometry.Len() == 0 {
+			return nil
+		}
+
+		shadow, err := world.Apply(EdgeMatrix(geometry))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < shadow.Len(); i++ {
+			p := columnVector(shadow, i)
+			p = p.Sub(d.Scale((plane.Normal.Dot(p) - plane.Offset) / facing))
+			shadow[0][i], shadow[1][i], shadow[2][i] = p[0], p[1], p[2]
+		}
+		return r.FillPolygons(PolygonMatrix(shadow))
+	})
+}
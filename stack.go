@@ -0,0 +1,106 @@
+// stack provides a stack of 4x4 transforms, or relative coordinate systems,
+// for building hierarchical scenes where each part is placed relative to the
+// part it hangs from.
+package main
+
+// Stack is a stack of 4x4 transforms whose top is the current coordinate
+// system. Pushing copies the top, so that transforms made after a push are
+// undone by the matching pop.
+type Stack [][][]float64
+
+// NewStack creates a stack holding only an identity transform. It returns the
+// new stack.
+func NewStack() Stack {
+	var s Stack
+	s.Push()
+	return s
+}
+
+// Len returns the number of transforms on the stack.
+func (s Stack) Len() int {
+	return len(s)
+}
+
+// Peek returns the transform on top of the stack, which may be changed in
+// place. If the stack is empty, an identity transform is pushed first.
+func (s *Stack) Peek() [][]float64 {
+	if len(*s) == 0 {
+		s.Push()
+	}
+	return (*s)[len(*s)-1]
+}
+
+// Push pushes a copy of the top transform onto the stack. If the stack is
+// empty, it pushes an identity transform.
+func (s *Stack) Push() {
+	if len(*s) == 0 {
+		top := NewMatrix()
+		MakeIdentity(top)
+		*s = append(*s, top)
+		return
+	}
+	*s = append(*s, DeepCopy(s.Peek()))
+}
+
+// Pop removes the top transform from the stack. The last transform is never
+// removed, so the stack always has a coordinate system.
+func (s *Stack) Pop() {
+	if len(*s) > 1 {
+		*s = (*s)[:len(*s)-1]
+	}
+}
+
+// Transform multiplies the top transform of the stack by m, so that m works in
+// the coordinate system of the top as TransformLocal does: after "move 10 0 0"
+// a rotation spins about (10, 0, 0), and each new part of a hierarchy is
+// placed relative to the part it hangs from. It returns an error if m is not a
+// 4x4 matrix.
+func (s *Stack) Transform(m [][]float64) error {
+	return s.TransformLocal(m)
+}
+
+// TransformLocal multiplies m by the top transform of the stack, so that m
+// works in the coordinate system of the top: a rotation spins about the top's
+// origin rather than the world's. This is how nested parts of a hierarchy are
+// placed. It returns an error if m is not a 4x4 matrix.
+func (s *Stack) TransformLocal(m [][]float64) error {
+	if _, err := NewTransform(m); err != nil {
+		return err
+	}
+
+	top := s.Peek()
+	if err := MultiplyMatrices(&top, &m); err != nil {
+		return err
+	}
+	(*s)[len(*s)-1] = m
+	return nil
+}
+
+// TransformWorld multiplies the top transform of the stack by m the other
+// way around, so that m is applied after every transform already on the top,
+// in the world's coordinate system. This is how the transforms of a script
+// build up before an apply. It returns an error if m is not a 4x4 matrix.
+func (s *Stack) TransformWorld(m [][]float64) error {
+	if _, err := NewTransform(m); err != nil {
+		return err
+	}
+
+	s.Peek()
+	return MultiplyMatrices(&m, &(*s)[len(*s)-1])
+}
+
+// Relative builds a primitive with build in a new edge matrix, places it in
+// the coordinate system on top of the stack, and adds it to edges. It returns
+// an error if build does or if edges is malformed.
+func (s *Stack) Relative(edges EdgeMatrix, build func(m EdgeMatrix) error) error {
+	primitive := NewEdgeMatrix()
+	if err := build(primitive); err != nil {
+		return err
+	}
+
+	top := s.Peek()
+	if err := MultiplyMatrices(&top, (*[][]float64)(&primitive)); err != nil {
+		return err
+	}
+	return edges.Append(primitive)
+}
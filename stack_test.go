@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// closeTo reports whether got and want hold the same numbers to within 1e-9.
+func closeTo(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStackTransformIsRelative(t *testing.T) {
+	for _, name := range []string{"Transform", "TransformLocal"} {
+		s := NewStack()
+		transform := s.Transform
+		if name == "TransformLocal" {
+			transform = s.TransformLocal
+		}
+		if err := transform(MakeTranslationMatrix(10, 0, 0)); err != nil {
+			t.Fatal(err)
+		}
+		if err := transform(MakeRotZ(Degrees(90))); err != nil {
+			t.Fatal(err)
+		}
+
+		edges := NewEdgeMatrix()
+		if err := s.Relative(edges, func(m EdgeMatrix) error { return m.AddEdge(1, 0, 0, 2, 0, 0) }); err != nil {
+			t.Fatal(err)
+		}
+		if !closeTo(edges[0], []float64{10, 10}) || !closeTo(edges[1], []float64{1, 2}) {
+			t.Errorf("%s: line is at x %v, y %v; want x [10 10], y [1 2]", name, edges[0], edges[1])
+		}
+	}
+}
+
+func TestStackTransformWorld(t *testing.T) {
+	s := NewStack()
+	s.TransformWorld(MakeTranslationMatrix(10, 0, 0))
+	s.TransformWorld(MakeRotZ(Degrees(90)))
+
+	edges := NewEdgeMatrix()
+	s.Relative(edges, func(m EdgeMatrix) error { return m.AddEdge(1, 0, 0, 2, 0, 0) })
+	if !closeTo(edges[0], []float64{0, 0}) || !closeTo(edges[1], []float64{11, 12}) {
+		t.Errorf("line is at x %v, y %v; want x [0 0], y [11 12]", edges[0], edges[1])
+	}
+}
+
+func TestStackPushPop(t *testing.T) {
+	s := NewStack()
+	s.Push()
+	s.Transform(MakeTranslationMatrix(5, 0, 0))
+	if s.Len() != 2 {
+		t.Fatalf("stack holds %d transforms after a push, want 2", s.Len())
+	}
+
+	s.Pop()
+	s.Pop()
+	if s.Len() != 1 {
+		t.Fatalf("stack holds %d transforms after popping everything, want 1", s.Len())
+	}
+	if got := s.Peek()[0][3]; got != 0 {
+		t.Errorf("top moves x by %g after the pop, want 0", got)
+	}
+}
+
+func TestStackTransformRejectsMalformed(t *testing.T) {
+	s := NewStack()
+	for _, transform := range []func([][]float64) error{s.Transform, s.TransformLocal, s.TransformWorld} {
+		if err := transform([][]float64{{1, 0}, {0, 1}}); err == nil {
+			t.Error("transforming by a 2x2 matrix succeeded")
+		}
+	}
+}
+
+func TestScriptApplyTransformsOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []Command
+		x, y     []float64
+	}{
+		{"move", []Command{
+			{Name: "move", Args: []string{"10", "0", "0"}},
+			{Name: "line", Args: []string{"0", "0", "0", "1", "0", "0"}},
+			{Name: "apply"},
+		}, []float64{10, 11}, []float64{0, 0}},
+		{"rotate then move", []Command{
+			{Name: "rotate", Args: []string{"z", "90"}},
+			{Name: "move", Args: []string{"10", "0", "0"}},
+			{Name: "line", Args: []string{"1", "0", "0", "2", "0", "0"}},
+			{Name: "apply"},
+		}, []float64{10, 10}, []float64{1, 2}},
+		{"no apply", []Command{
+			{Name: "move", Args: []string{"10", "0", "0"}},
+			{Name: "line", Args: []string{"0", "0", "0", "1", "0", "0"}},
+		}, []float64{0, 1}, []float64{0, 0}},
+	}
+
+	for _, test := range tests {
+		r := NewRenderer()
+		edges := NewEdgeMatrix()
+		for _, c := range test.commands {
+			if err := runCommand(context.Background(), c, r, &edges, nil); err != nil {
+				t.Fatalf("%s: %s: %v", test.name, c.Name, err)
+			}
+		}
+		if !closeTo(edges[0], test.x) || !closeTo(edges[1], test.y) {
+			t.Errorf("%s: line is at x %v, y %v; want x %v, y %v", test.name, edges[0], edges[1], test.x, test.y)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// stereo provides stereo rendering of left and right eye views, combined side
+// by side or as a red-cyan anaglyph.
+package main
+
+// StereoMode is how the two views of a stereo render are combined.
+type StereoMode int
+
+const (
+	// SideBySide places the left eye's view to the left of the right eye's.
+	SideBySide StereoMode = iota
+	// Anaglyph takes red from the left eye's view and green and blue from the
+	// right eye's, for viewing with red-cyan glasses.
+	Anaglyph
+)
+
+// RenderStereo draws a scene twice, once for each eye, with renderers set up
+// like r but turned by half of separation about the vertical axis through the
+// center of the screen, one each way. draw is called with each eye's renderer.
+// It returns the views combined as mode says.
+func RenderStereo(r *Renderer, separation Angle, mode StereoMode, draw func(eye *Renderer) error) ([][][]int, error) {
+	var views [2][][][]int
+	for i, side := range []float64{-0.5, 0.5} {
+		eye := r.fork()
+		turn := r.aboutScreenCenter(MakeRotY(separation.Scale(side)))
+		MultiplyMatrices(&turn, &eye.Camera)
+
+		eye.Clear()
+		if err := draw(eye); err != nil {
+			return nil, err
+		}
+		views[i] = eye.Output()
+	}
+
+	if mode == Anaglyph {
+		return combineAnaglyph(views[0], views[1]), nil
+	}
+	return combineSideBySide(views[0], views[1]), nil
+}
+
+// fork creates a renderer set up like r, with a new screen of the same size as
+// r's and its own copies of r's color, transforms, camera, and the rest of its
+// state. It returns the new renderer.
+func (r *Renderer) fork() *Renderer {
+	return r.forkSize(screenWidth(r.Screen), len(r.Screen))
+}
+
+// forkSize is like fork but gives the new renderer a screen of size width by
+// height.
+func (r *Renderer) forkSize(width, height int) *Renderer {
+	// Copying the whole renderer keeps every setting, including ones added
+	// later; only what the fork draws into or may change is made anew.
+	f := *r
+	f.Screen = NewScreen(width, height)
+	f.ZBuffer = NewZBuffer(width, height)
+	f.Target = ScreenRasterizer{f.Screen, f.ZBuffer}
+	f.glow = nil
+
+	f.Color = append([]int(nil), r.Color...)
+	f.Stack = nil
+	for _, m := range r.Stack {
+		f.Stack = append(f.Stack, DeepCopy(m))
+	}
+	f.Camera = DeepCopy(r.Camera)
+	f.Lights = append([]Light(nil), r.Lights...)
+	f.Ambient = append([]int(nil), r.Ambient...)
+	f.Emissive = append([]int(nil), r.Emissive...)
+	f.BackColor = append([]int(nil), r.BackColor...)
+	f.Filters = append([]Filter(nil), r.Filters...)
+	f.Passes = append([]Pass(nil), r.Passes...)
+	f.ClipPlanes = append([]ClipPlane(nil), r.ClipPlanes...)
+	return &f
+}
+
+// combineSideBySide places two screens of the same size next to each other. It
+// returns the combined screen.
+func combineSideBySide(left, right [][][]int) [][][]int {
+	out := make([][][]int, len(left))
+	for i := range out {
+		out[i] = append(append([][]int{}, left[i]...), right[i]...)
+	}
+	return out
+}
+
+// combineAnaglyph takes the red channel of left and the green and blue
+// channels of right, both converted to gray first so colors don't bleed
+// between eyes. It returns the combined screen.
+func combineAnaglyph(left, right [][][]int) [][][]int {
+	out := newScreenLike(left)
+	for i := range out {
+		for j := range out[i] {
+			l, r := luminance(left[i][j]), luminance(right[i][j])
+			out[i][j] = []int{l, r, r}
+		}
+	}
+	return out
+}
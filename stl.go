@@ -0,0 +1,181 @@
+// stl provides reading and writing of STL files, in both their binary and ASCII
+// forms, so that generated geometry can be 3D printed and printable models
+// can be drawn.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stlHeaderSize is the size in bytes of the header of a binary STL file, and
+// stlTriangleSize the size of each triangle after it.
+const (
+	stlHeaderSize   = 80
+	stlTriangleSize = 50
+)
+
+// ReadSTL reads the triangles of an STL file from r into a polygon matrix,
+// telling binary files from ASCII ones by their size. Stored normals are
+// ignored in favor of the winding of each triangle's corners. It returns an
+// error if the data is not a well-formed STL file.
+func ReadSTL(r io.Reader) (PolygonMatrix, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// ASCII files start with "solid", but so do some binary files, whose size
+	// is fixed by the triangle count in their header.
+	if len(data) >= stlHeaderSize+4 {
+		count := binary.LittleEndian.Uint32(data[stlHeaderSize:])
+		if uint64(len(data)) == stlHeaderSize+4+uint64(count)*stlTriangleSize {
+			return readBinarySTL(data[stlHeaderSize+4:], int(count)), nil
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return readASCIISTL(data)
+	}
+	return nil, fmt.Errorf("stl: data is neither a binary nor an ASCII STL file")
+}
+
+// readBinarySTL reads count triangles in the binary STL format from data.
+func readBinarySTL(data []byte, count int) PolygonMatrix {
+	polygons := NewPolygonMatrix()
+	for i := 0; i < count; i++ {
+		triangle := data[i*stlTriangleSize:]
+		// Each corner follows the normal's 12 bytes.
+		for v := 1; v <= 3; v++ {
+			var p [3]float64
+			for j := range p {
+				bits := binary.LittleEndian.Uint32(triangle[12*v+4*j:])
+				p[j] = float64(math.Float32frombits(bits))
+			}
+			EdgeMatrix(polygons).AddPoint(p[0], p[1], p[2])
+		}
+	}
+	return polygons
+}
+
+// readASCIISTL reads the corners of every vertex record of ASCII STL data. It
+// returns an error if a vertex record is malformed or the corners do not make
+// whole triangles.
+func readASCIISTL(data []byte) (PolygonMatrix, error) {
+	polygons := NewPolygonMatrix()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "vertex" {
+			continue
+		}
+
+		p, err := parseFloats(fields[1:])
+		if err != nil || len(p) != 3 {
+			return nil, fmt.Errorf("stl line %d: vertex needs 3 numbers, got %q", line, strings.Join(fields[1:], " "))
+		}
+		EdgeMatrix(polygons).AddPoint(p[0], p[1], p[2])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := polygons.Validate(); err != nil {
+		return nil, fmt.Errorf("stl: %v", err)
+	}
+	return polygons, nil
+}
+
+// LoadSTL reads the triangles of the STL file named filename into a polygon
+// matrix. It returns an error if the file cannot be read or is malformed.
+func LoadSTL(filename string) (PolygonMatrix, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	polygons, err := ReadSTL(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return polygons, nil
+}
+
+// WriteSTL writes the triangles of a polygon matrix to w as a binary STL file,
+// with each triangle's normal taken from the winding of its corners. It
+// returns an error if the polygon matrix is malformed or the data cannot be
+// written.
+func WriteSTL(w io.Writer, polygons PolygonMatrix) error {
+	if err := polygons.Validate(); err != nil {
+		return err
+	}
+
+	count := polygons.Len() / 3
+	data := make([]byte, stlHeaderSize+4+count*stlTriangleSize)
+	copy(data, "binary STL")
+	binary.LittleEndian.PutUint32(data[stlHeaderSize:], uint32(count))
+
+	for i := 0; i < count; i++ {
+		triangle := data[stlHeaderSize+4+i*stlTriangleSize:]
+		values := []Vector{SurfaceNormal(polygons, 3*i).Normalize()}
+		for v := 0; v < 3; v++ {
+			values = append(values, columnVector(polygons, 3*i+v))
+		}
+		for v, value := range values {
+			for j, x := range value {
+				binary.LittleEndian.PutUint32(triangle[12*v+4*j:], math.Float32bits(float32(x)))
+			}
+		}
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// WriteASCIISTL writes the triangles of a polygon matrix to w as an ASCII STL
+// solid called name. It returns an error if the polygon matrix is malformed or
+// the data cannot be written.
+func WriteASCIISTL(w io.Writer, polygons PolygonMatrix, name string) error {
+	if err := polygons.Validate(); err != nil {
+		return err
+	}
+
+	format := func(v Vector) string {
+		return strconv.FormatFloat(v[0], 'e', -1, 64) + " " +
+			strconv.FormatFloat(v[1], 'e', -1, 64) + " " +
+			strconv.FormatFloat(v[2], 'e', -1, 64)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "solid %s\n", name)
+	for i := 0; i+2 < polygons.Len(); i += 3 {
+		fmt.Fprintf(bw, "  facet normal %s\n    outer loop\n", format(SurfaceNormal(polygons, i).Normalize()))
+		for v := 0; v < 3; v++ {
+			fmt.Fprintf(bw, "      vertex %s\n", format(columnVector(polygons, i+v)))
+		}
+		fmt.Fprintf(bw, "    endloop\n  endfacet\n")
+	}
+	fmt.Fprintf(bw, "endsolid %s\n", name)
+	return bw.Flush()
+}
+
+// SaveSTL writes the triangles of a polygon matrix to the file named filename
+// as a binary STL file. It returns an error if the file cannot be written.
+func SaveSTL(filename string, polygons PolygonMatrix) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteSTL(file, polygons); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
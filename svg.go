@@ -0,0 +1,249 @@
+// svg provides a Rasterizer that draws scenes as SVG images, whose lines and
+// triangles stay sharp at any size, for print and for figures.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SVGRasterizer is a Rasterizer that draws onto an SVG image of size Width by
+// Height as shapes rather than pixels. Coordinates are laid out as on a
+// screen of the same size, with y increasing upward, and rounded to
+// thousandths of a pixel. Shapes are painted from the farthest to the nearest
+// by their mean depth, in the order they were drawn among shapes at the same
+// depth, so flat drawings keep their order. Runs of lines of the same color
+// and width that each start within half a pixel of where the last one ended,
+// such as the many short edges AddCurve and AddCircle add, are joined into a
+// single path. SVG has no way to blend three colors across a triangle, so
+// shaded triangles are filled with the color at their center. Colors with
+// fewer than three channels are drawn black, the default draw color.
+type SVGRasterizer struct {
+	Width, Height int
+
+	defs   []string
+	shapes []svgShape
+	run    svgRun
+}
+
+// svgShape is the markup of a shape of an SVG image and its depth.
+type svgShape struct {
+	markup string
+	depth  float64
+}
+
+// svgRun is a run of joined lines that has not been added to an SVG image
+// yet: the attributes they are stroked with and the points they join.
+type svgRun struct {
+	stroke string
+	points [][3]float64
+}
+
+// NewSVGRasterizer creates an SVG rasterizer with an empty image of size width
+// by height. It returns the new rasterizer.
+func NewSVGRasterizer(width, height int) *SVGRasterizer {
+	return &SVGRasterizer{Width: width, Height: height}
+}
+
+// Plot draws a point (x, y, z) as a square one pixel across.
+func (s *SVGRasterizer) Plot(x, y, z float64, color []int) {
+	if !finite(x, y, z) {
+		return
+	}
+	s.add(z, fmt.Sprintf(`<rect x="%s" y="%s" width="1" height="1" fill="%s"/>`,
+		svgNumber(x), svgNumber(float64(s.Height)-1-y), svgColor(color)))
+}
+
+// DrawLine draws a line from (x0, y0, z0) to (x1, y1, z1). Options may
+// override the color and set the width and gradient of the line; the others
+// only matter to pixels.
+func (s *SVGRasterizer) DrawLine(x0, y0, z0, x1, y1, z1 float64, color []int, opts ...DrawOption) {
+	if !finite(x0, y0, z0, x1, y1, z1) {
+		return
+	}
+
+	o := newDrawOptions(color, opts...)
+	stroke := fmt.Sprintf(`stroke="%s"`, svgColor(o.color))
+	if c0, c1 := o.gradient[0], o.gradient[1]; len(c0) >= 3 && len(c1) >= 3 {
+		// Each gradient runs along its own line, so lines with gradients
+		// are never joined.
+		id := fmt.Sprintf("gradient%d", len(s.defs))
+		sx0, sy0 := s.coords(x0, y0)
+		sx1, sy1 := s.coords(x1, y1)
+		s.defs = append(s.defs, fmt.Sprintf(`<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s"><stop offset="0" stop-color="%s"/><stop offset="1" stop-color="%s"/></linearGradient>`,
+			id, sx0, sy0, sx1, sy1, svgColor(c0), svgColor(c1)))
+		stroke = fmt.Sprintf(`stroke="url(#%s)"`, id)
+	}
+	if o.width > 1 {
+		stroke += fmt.Sprintf(` stroke-width="%d"`, o.width)
+	}
+
+	if n := len(s.run.points); n > 0 && s.run.stroke == stroke {
+		if last := s.run.points[n-1]; math.Hypot(x0-last[0], y0-last[1]) <= 0.5 {
+			s.run.points = append(s.run.points, [3]float64{x1, y1, z1})
+			return
+		}
+	}
+	s.flush()
+	s.run = svgRun{stroke, [][3]float64{{x0, y0, z0}, {x1, y1, z1}}}
+}
+
+// FillTriangle fills the triangle with corners (x0, y0, z0), (x1, y1, z1), and
+// (x2, y2, z2).
+func (s *SVGRasterizer) FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color []int) {
+	if !finite(x0, y0, z0, x1, y1, z1, x2, y2, z2) {
+		return
+	}
+
+	points := make([]string, 3)
+	for i, p := range [][2]float64{{x0, y0}, {x1, y1}, {x2, y2}} {
+		x, y := s.coords(p[0], p[1])
+		points[i] = x + "," + y
+	}
+	s.add((z0+z1+z2)/3, fmt.Sprintf(`<polygon points="%s" fill="%s"/>`, strings.Join(points, " "), svgColor(color)))
+}
+
+// ShadeTriangle fills the triangle with corners (x0, y0, z0), (x1, y1, z1),
+// and (x2, y2, z2) with the mean of the corner colors c0, c1, and c2.
+func (s *SVGRasterizer) ShadeTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, c0, c1, c2 []int) {
+	s.FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2, func(w0, w1, w2 float64) []int {
+		color := make([]int, len(c0))
+		for i := range color {
+			if i < len(c1) && i < len(c2) {
+				color[i] = clampChannel(w0*float64(c0[i]) + w1*float64(c1[i]) + w2*float64(c2[i]))
+			}
+		}
+		return color
+	})
+}
+
+// FillTriangleFunc fills the triangle with corners (x0, y0, z0), (x1, y1, z1),
+// and (x2, y2, z2) with the color returned by color for the barycentric
+// weights of its center.
+func (s *SVGRasterizer) FillTriangleFunc(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64, color func(w0, w1, w2 float64) []int) {
+	if !finite(x0, y0, z0, x1, y1, z1, x2, y2, z2) {
+		return
+	}
+	s.FillTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2, color(1.0/3, 1.0/3, 1.0/3))
+}
+
+// Encode writes the image drawn so far to w. It returns an error if the image
+// cannot be written.
+func (s *SVGRasterizer) Encode(w io.Writer) error {
+	s.flush()
+	shapes := append([]svgShape{}, s.shapes...)
+	sort.SliceStable(shapes, func(i, j int) bool {
+		return shapes[i].depth < shapes[j].depth
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", s.Width, s.Height, s.Width, s.Height)
+	if len(s.defs) > 0 {
+		fmt.Fprintf(bw, "<defs>\n%s\n</defs>\n", strings.Join(s.defs, "\n"))
+	}
+	fmt.Fprintf(bw, "<g stroke-linecap=\"round\" stroke-linejoin=\"round\">\n")
+	for _, shape := range shapes {
+		fmt.Fprintln(bw, shape.markup)
+	}
+	fmt.Fprintf(bw, "</g>\n</svg>\n")
+	return bw.Flush()
+}
+
+// Save writes the image drawn so far to the file named filename. It returns an
+// error if the file cannot be written.
+func (s *SVGRasterizer) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// add adds a shape at a depth to the image, after any run of lines before it.
+func (s *SVGRasterizer) add(depth float64, markup string) {
+	s.flush()
+	s.shapes = append(s.shapes, svgShape{markup, depth})
+}
+
+// flush adds the run of joined lines being drawn to the image, as a line if
+// it has one or a path if it has more.
+func (s *SVGRasterizer) flush() {
+	points := s.run.points
+	if len(points) == 0 {
+		return
+	}
+	s.run.points = nil
+
+	depth := 0.0
+	coords := make([]string, len(points))
+	for i, p := range points {
+		x, y := s.coords(p[0], p[1])
+		coords[i] = x + "," + y
+		depth += p[2] / float64(len(points))
+	}
+
+	var markup string
+	if len(points) == 2 {
+		x0, y0 := s.coords(points[0][0], points[0][1])
+		x1, y1 := s.coords(points[1][0], points[1][1])
+		markup = fmt.Sprintf(`<line x1="%s" y1="%s" x2="%s" y2="%s" fill="none" %s/>`, x0, y0, x1, y1, s.run.stroke)
+	} else {
+		markup = fmt.Sprintf(`<path d="M%s L%s" fill="none" %s/>`, coords[0], strings.Join(coords[1:], " "), s.run.stroke)
+	}
+	s.shapes = append(s.shapes, svgShape{markup, depth})
+}
+
+// coords returns the SVG coordinates of the center of the pixel at (x, y) of a
+// screen the size of the image. Pixel centers lie half a pixel in from the
+// corners of their pixels, and rows are counted down from the top.
+func (s *SVGRasterizer) coords(x, y float64) (string, string) {
+	return svgNumber(x + 0.5), svgNumber(float64(s.Height) - 0.5 - y)
+}
+
+// svgNumber formats a coordinate rounded to thousandths of a pixel.
+func svgNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// svgColor formats a color as an SVG color, or black if it has fewer than
+// three channels.
+func svgColor(color []int) string {
+	if len(color) < 3 {
+		return "rgb(0,0,0)"
+	}
+	return fmt.Sprintf("rgb(%d,%d,%d)", clampInt(color[0], 0, 255), clampInt(color[1], 0, 255), clampInt(color[2], 0, 255))
+}
+
+// WriteSVG writes the edges of an edge matrix to w as an SVG image of size
+// width by height, stroked in color, instead of rasterizing them, as an
+// SVGRasterizer draws them. It returns an error if the edge matrix is
+// malformed or the image cannot be written.
+func WriteSVG(w io.Writer, edges EdgeMatrix, width, height int, color []int) error {
+	s := NewSVGRasterizer(width, height)
+	if err := RasterizeLines(edges, s, color); err != nil {
+		return err
+	}
+	return s.Encode(w)
+}
+
+// SaveSVG writes the edges of an edge matrix to the file named filename as an
+// SVG image, as WriteSVG does. It returns an error if the file cannot be
+// written.
+func SaveSVG(edges EdgeMatrix, width, height int, color []int, filename string) error {
+	s := NewSVGRasterizer(width, height)
+	if err := RasterizeLines(edges, s, color); err != nil {
+		return err
+	}
+	return s.Save(filename)
+}
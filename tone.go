@@ -0,0 +1,109 @@
+// tone provides luminance histograms and tone adjustments, such as levels,
+// curves, and auto-exposure, that are applied to a screen as filters.
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Histogram counts the pixels of a screen at each luminance from 0 to 255.
+func Histogram(screen [][][]int) [256]int {
+	var h [256]int
+	for _, row := range screen {
+		for _, rgb := range row {
+			h[clampInt(luminance(rgb), 0, 255)]++
+		}
+	}
+	return h
+}
+
+// Levels returns a filter that maps black and everything darker to 0, white
+// and everything brighter to 255, and the channels in between through the
+// given gamma. A gamma above 1 brightens the midtones.
+func Levels(black, white int, gamma float64) Filter {
+	var table [256]int
+	for v := range table {
+		t := 1.0
+		if white > black {
+			t = math.Max(0, math.Min(1, float64(v-black)/float64(white-black)))
+		} else if v < black {
+			t = 0
+		}
+		table[v] = clampChannel(math.Pow(t, 1/gamma) * 255)
+	}
+	return lookupFilter(table)
+}
+
+// Curves returns a filter that maps every channel through the piecewise-linear
+// tone curve joining the given (input, output) points, each from 0 to 255.
+// Channels beyond the first or last point keep that point's output.
+func Curves(points ...[2]float64) Filter {
+	sorted := append([][2]float64{}, points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	var table [256]int
+	for v := range table {
+		table[v] = v
+		if len(sorted) == 0 {
+			continue
+		}
+
+		x := float64(v)
+		out := sorted[len(sorted)-1][1]
+		if x <= sorted[0][0] {
+			out = sorted[0][1]
+		}
+		for i := 1; i < len(sorted); i++ {
+			a, b := sorted[i-1], sorted[i]
+			if x >= a[0] && x <= b[0] && b[0] > a[0] {
+				out = a[1] + (x-a[0])/(b[0]-a[0])*(b[1]-a[1])
+				break
+			}
+		}
+		table[v] = clampChannel(out)
+	}
+	return lookupFilter(table)
+}
+
+// AutoExposure returns a filter that stretches a screen's luminance so that
+// the darkest and brightest clip fraction of its pixels, such as 0.01, become
+// black and white.
+func AutoExposure(clip float64) Filter {
+	return func(screen [][][]int) [][][]int {
+		h := Histogram(screen)
+		total := 0
+		for _, count := range h {
+			total += count
+		}
+
+		limit := int(clip * float64(total))
+		black, white := 0, 255
+		for seen := 0; black < 255 && seen+h[black] <= limit; black++ {
+			seen += h[black]
+		}
+		for seen := 0; white > 0 && seen+h[white] <= limit; white-- {
+			seen += h[white]
+		}
+		if white <= black {
+			return screen
+		}
+		return Levels(black, white, 1)(screen)
+	}
+}
+
+// lookupFilter returns a filter that replaces every channel value v with
+// table[v].
+func lookupFilter(table [256]int) Filter {
+	return func(screen [][][]int) [][][]int {
+		out := newScreenLike(screen)
+		for i, row := range screen {
+			for j, rgb := range row {
+				for c := 0; c < 3; c++ {
+					out[i][j][c] = table[clampInt(rgb[c], 0, 255)]
+				}
+			}
+		}
+		return out
+	}
+}
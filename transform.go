@@ -0,0 +1,128 @@
+// transform provides the immutable Transform type. Every operation on a
+// Transform returns a new value, so transforms can be shared and cached freely.
+package main
+
+import (
+	"fmt"
+)
+
+// Transform is an immutable 4x4 transformation matrix. Its zero value is not
+// useful; start from IdentityTransform.
+type Transform struct {
+	m [4][4]float64
+}
+
+// IdentityTransform returns the transform that changes nothing.
+func IdentityTransform() Transform {
+	var t Transform
+	for i := range t.m {
+		t.m[i][i] = 1
+	}
+	return t
+}
+
+// NewTransform copies a 4x4 matrix into a transform. It returns an error if
+// the matrix is not 4x4.
+func NewTransform(matrix [][]float64) (Transform, error) {
+	var t Transform
+	if len(matrix) != 4 {
+		return t, fmt.Errorf("transform matrix has %d rows, want 4", len(matrix))
+	}
+	for i, row := range matrix {
+		if len(row) != 4 {
+			return t, fmt.Errorf("transform matrix row %d has %d columns, want 4", i, len(row))
+		}
+		copy(t.m[i][:], row)
+	}
+	return t, nil
+}
+
+// Matrix returns a new 4x4 matrix holding the transform.
+func (t Transform) Matrix() [][]float64 {
+	matrix := NewMatrix()
+	for i := range t.m {
+		copy(matrix[i], t.m[i][:])
+	}
+	return matrix
+}
+
+// Then returns the transform that applies t and then next.
+func (t Transform) Then(next Transform) Transform {
+	var product Transform
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				product.m[i][j] += next.m[i][k] * t.m[k][j]
+			}
+		}
+	}
+	return product
+}
+
+// Translate returns the transform that applies t and then moves by (x, y, z).
+func (t Transform) Translate(x, y, z float64) Transform {
+	return t.Then(mustTransform(MakeTranslationMatrix(x, y, z)))
+}
+
+// Scale returns the transform that applies t and then scales by (x, y, z).
+func (t Transform) Scale(x, y, z float64) Transform {
+	return t.Then(mustTransform(MakeDilationMatrix(x, y, z)))
+}
+
+// RotateX returns the transform that applies t and then rotates by theta about
+// the x axis.
+func (t Transform) RotateX(theta Angle) Transform {
+	return t.Then(mustTransform(MakeRotX(theta)))
+}
+
+// RotateY returns the transform that applies t and then rotates by theta about
+// the y axis.
+func (t Transform) RotateY(theta Angle) Transform {
+	return t.Then(mustTransform(MakeRotY(theta)))
+}
+
+// RotateZ returns the transform that applies t and then rotates by theta about
+// the z axis.
+func (t Transform) RotateZ(theta Angle) Transform {
+	return t.Then(mustTransform(MakeRotZ(theta)))
+}
+
+// Apply returns a transformed copy of an edge matrix, leaving the original
+// unchanged. It returns an error if the edge matrix is malformed.
+func (t Transform) Apply(edges EdgeMatrix) (EdgeMatrix, error) {
+	if err := edges.checkShape(); err != nil {
+		return nil, err
+	}
+
+	out := edges.Clone()
+	matrix := t.Matrix()
+	err := MultiplyMatrices(&matrix, (*[][]float64)(&out))
+	return out, err
+}
+
+// mustTransform copies a matrix known to be 4x4 into a transform.
+func mustTransform(matrix [][]float64) Transform {
+	t, err := NewTransform(matrix)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// BakeTransform applies the 4x4 transform m to the points of an edge matrix in
+// place and then resets m to the identity, so the geometry keeps its
+// transformed position without needing the transform any more. It returns an
+// error, leaving both unchanged, if either is malformed.
+func BakeTransform(edges *EdgeMatrix, m [][]float64) error {
+	if err := edges.checkShape(); err != nil {
+		return err
+	} else if _, err := NewTransform(m); err != nil {
+		return err
+	}
+
+	if err := MultiplyMatrices(&m, (*[][]float64)(edges)); err != nil {
+		return err
+	}
+	MakeIdentity(m)
+	return nil
+}
@@ -0,0 +1,109 @@
+// turtle provides turtle graphics: a pen moved by forward steps and turns that
+// draws its path into an edge matrix.
+package main
+
+import "math"
+
+// Turtle is a pen at a point of the xy plane, facing Heading, which is
+// measured counterclockwise from the x axis. While its pen is down, every move
+// adds an edge to Edges.
+type Turtle struct {
+	X, Y    float64
+	Heading Angle
+	Edges   EdgeMatrix
+
+	up    bool
+	saved []turtleState
+}
+
+// turtleState is the part of a turtle saved by Push.
+type turtleState struct {
+	x, y    float64
+	heading Angle
+	up      bool
+}
+
+// NewTurtle creates a turtle at (x, y) facing along the x axis with its pen
+// down and an empty edge matrix. It returns the new turtle.
+func NewTurtle(x, y float64) *Turtle {
+	return &Turtle{X: x, Y: y, Heading: Degrees(0), Edges: NewEdgeMatrix()}
+}
+
+// Forward moves the turtle distance units along its heading.
+func (t *Turtle) Forward(distance float64) {
+	radians := t.Heading.Radians()
+	x := t.X + distance*math.Cos(radians)
+	y := t.Y + distance*math.Sin(radians)
+	if !t.up {
+		t.Edges.AddEdge(t.X, t.Y, 0, x, y, 0)
+	}
+	t.X, t.Y = x, y
+}
+
+// Back moves the turtle distance units against its heading.
+func (t *Turtle) Back(distance float64) {
+	t.Forward(-distance)
+}
+
+// Left turns the turtle counterclockwise by angle.
+func (t *Turtle) Left(angle Angle) {
+	t.Heading = t.Heading.Add(angle)
+}
+
+// Right turns the turtle clockwise by angle.
+func (t *Turtle) Right(angle Angle) {
+	t.Heading = t.Heading.Add(angle.Scale(-1))
+}
+
+// PenUp lifts the turtle's pen so that it moves without drawing.
+func (t *Turtle) PenUp() {
+	t.up = true
+}
+
+// PenDown lowers the turtle's pen so that it draws as it moves.
+func (t *Turtle) PenDown() {
+	t.up = false
+}
+
+// Push saves the turtle's position, heading, and pen.
+func (t *Turtle) Push() {
+	t.saved = append(t.saved, turtleState{t.X, t.Y, t.Heading, t.up})
+}
+
+// Pop restores the turtle's position, heading, and pen to what they were at
+// the matching Push, without drawing. It does nothing if nothing was pushed.
+func (t *Turtle) Pop() {
+	if len(t.saved) == 0 {
+		return
+	}
+
+	s := t.saved[len(t.saved)-1]
+	t.saved = t.saved[:len(t.saved)-1]
+	t.X, t.Y, t.Heading, t.up = s.x, s.y, s.heading, s.up
+}
+
+// Run moves the turtle by a program of one-letter commands, as produced by
+// L-systems: F draws forward distance units, f moves forward without drawing,
+// + and - turn left and right by angle, [ and ] push and pop, and every other
+// letter is ignored.
+func (t *Turtle) Run(program string, distance float64, angle Angle) {
+	for _, c := range program {
+		switch c {
+		case 'F':
+			t.Forward(distance)
+		case 'f':
+			up := t.up
+			t.PenUp()
+			t.Forward(distance)
+			t.up = up
+		case '+':
+			t.Left(angle)
+		case '-':
+			t.Right(angle)
+		case '[':
+			t.Push()
+		case ']':
+			t.Pop()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// vector provides the Vector type and the usual operations on 3D vectors.
+package main
+
+import (
+	"math"
+)
+
+// Vector is a 3D vector or point.
+type Vector [3]float64
+
+// Add returns the sum of two vectors.
+func (v Vector) Add(w Vector) Vector {
+	return Vector{v[0] + w[0], v[1] + w[1], v[2] + w[2]}
+}
+
+// Sub returns the difference of two vectors.
+func (v Vector) Sub(w Vector) Vector {
+	return Vector{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+// Scale returns the vector multiplied by f.
+func (v Vector) Scale(f float64) Vector {
+	return Vector{v[0] * f, v[1] * f, v[2] * f}
+}
+
+// Dot returns the dot product of two vectors.
+func (v Vector) Dot(w Vector) float64 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+// Cross returns the cross product of two vectors.
+func (v Vector) Cross(w Vector) Vector {
+	return Vector{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+// Length returns the length of the vector.
+func (v Vector) Length() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Normalize returns the vector scaled to length 1. The zero vector is returned
+// unchanged.
+func (v Vector) Normalize() Vector {
+	length := v.Length()
+	if length == 0 {
+		return v
+	}
+	return v.Scale(1 / length)
+}
@@ -0,0 +1,81 @@
+// vectorfield provides flow visualization of vector fields as arrows and
+// streamlines in an edge matrix.
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// VectorField returns the vector of a field, such as the velocity of a flow,
+// at p.
+type VectorField func(p Vector) Vector
+
+// AddArrows adds an arrow for every point of a grid of spacing units covering
+// the box from min to max to the edge matrix. Each arrow starts at its grid
+// point and is the field's vector there multiplied by scale. It returns an
+// error if spacing is not positive.
+func (m EdgeMatrix) AddArrows(field VectorField, min, max Vector, spacing, scale float64) error {
+	if !(spacing > 0) || math.IsInf(spacing, 1) {
+		return fmt.Errorf("arrows: spacing %g is not a positive number", spacing)
+	}
+
+	for x := min[0]; x <= max[0]; x += spacing {
+		for y := min[1]; y <= max[1]; y += spacing {
+			for z := min[2]; z <= max[2]; z += spacing {
+				p := Vector{x, y, z}
+				m.addArrow(p, p.Add(field(p).Scale(scale)))
+			}
+		}
+	}
+	return nil
+}
+
+// addArrow adds an arrow from tail to head to the edge matrix, with a head a
+// quarter of its length drawn in the plane of the arrow and the z axis, or the
+// y axis for arrows along z.
+func (m EdgeMatrix) addArrow(tail, head Vector) {
+	d := head.Sub(tail)
+	length := d.Length()
+	if length == 0 || !finite(head[0], head[1], head[2]) {
+		return
+	}
+
+	up := Vector{0, 0, 1}
+	if math.Abs(d.Normalize().Dot(up)) > 0.99 {
+		up = Vector{0, 1, 0}
+	}
+	side := d.Cross(up).Normalize().Scale(length / 8)
+	back := head.Sub(d.Scale(0.25))
+
+	addVectorEdge(m, tail, head)
+	addVectorEdge(m, head, back.Add(side))
+	addVectorEdge(m, head, back.Sub(side))
+}
+
+// AddStreamline adds the path a particle released at seed takes through the
+// field to the edge matrix, traced with the fourth-order Runge-Kutta method
+// for the given number of steps of step time units each. Tracing stops early
+// where the field vanishes or stops being finite. It returns an error if step
+// is not a finite nonzero number.
+func (m EdgeMatrix) AddStreamline(field VectorField, seed Vector, step float64, steps int) error {
+	if step == 0 || math.IsNaN(step) || math.IsInf(step, 0) {
+		return fmt.Errorf("streamline: step %g is not a finite nonzero number", step)
+	}
+
+	p := seed
+	for i := 0; i < steps; i++ {
+		k1 := field(p)
+		k2 := field(p.Add(k1.Scale(step / 2)))
+		k3 := field(p.Add(k2.Scale(step / 2)))
+		k4 := field(p.Add(k3.Scale(step)))
+		next := p.Add(k1.Add(k2.Scale(2)).Add(k3.Scale(2)).Add(k4).Scale(step / 6))
+
+		if next == p || !finite(next[0], next[1], next[2]) {
+			break
+		}
+		addVectorEdge(m, p, next)
+		p = next
+	}
+	return nil
+}
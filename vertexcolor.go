@@ -0,0 +1,46 @@
+// vertexcolor provides per-vertex colors for meshes and drawing meshes with
+// their colors blended across each face.
+package main
+
+// Paint returns a copy of the mesh whose vertex colors are set by calling
+// color with each vertex.
+func (m Mesh) Paint(color func(v Vector) []int) Mesh {
+	painted := Mesh{Vertices: m.Vertices, Faces: m.Faces}
+	painted.Colors = make([][]int, len(m.Vertices))
+	for i, v := range m.Vertices {
+		painted.Colors[i] = color(v)
+	}
+	return painted
+}
+
+// DrawMesh fills every face of a mesh onto the renderer's target, as seen
+// through its camera. If the mesh has vertex colors they are blended across
+// each face; otherwise faces are filled with the renderer's current color.
+// Faces facing away are skipped if the renderer culls back faces. It returns an
+// error if a face has a corner outside the list of vertices or the mesh has
+// colors but not an RGB color for every vertex.
+func (r *Renderer) DrawMesh(m Mesh) error {
+	if err := m.checkShape(); err != nil {
+		return err
+	}
+
+	view, err := r.view(EdgeMatrix(m.Triangles()))
+	if err != nil {
+		return err
+	}
+
+	for i, f := range m.Faces {
+		if r.culled(view, 3*i) {
+			continue
+		}
+
+		c := [3][]int{r.Color, r.Color, r.Color}
+		if len(m.Colors) > 0 {
+			c = [3][]int{m.Colors[f[0]], m.Colors[f[1]], m.Colors[f[2]]}
+		}
+
+		p0, p1, p2 := view.Column(3*i), view.Column(3*i+1), view.Column(3*i+2)
+		r.Target.ShadeTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], c[0], c[1], c[2])
+	}
+	return nil
+}
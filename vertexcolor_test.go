@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+// triangleMesh returns a mesh of one triangle in the middle of the screen.
+func triangleMesh() Mesh {
+	return Mesh{
+		Vertices: []Vector{{100, 100, 0}, {400, 100, 0}, {250, 400, 0}},
+		Faces:    [][3]int{{0, 1, 2}},
+	}
+}
+
+func TestDrawMeshChecksMesh(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Mesh)
+		ok     bool
+	}{
+		{"no colors", func(m *Mesh) {}, true},
+		{"a color per vertex", func(m *Mesh) { m.Colors = [][]int{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}} }, true},
+		{"too few colors", func(m *Mesh) { m.Colors = [][]int{{255, 0, 0}} }, false},
+		{"short colors", func(m *Mesh) { m.Colors = [][]int{{255}, {0}, {0}} }, false},
+		{"corner past the vertices", func(m *Mesh) { m.Faces = append(m.Faces, [3]int{0, 1, 3}) }, false},
+		{"negative corner", func(m *Mesh) { m.Faces = append(m.Faces, [3]int{-1, 1, 2}) }, false},
+	}
+
+	for _, test := range tests {
+		m := triangleMesh()
+		test.change(&m)
+		err := NewRenderer().DrawMesh(m)
+		if test.ok && err != nil {
+			t.Errorf("%s: DrawMesh returned %v", test.name, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("%s: DrawMesh succeeded", test.name)
+		}
+	}
+}
+
+func TestDrawMeshBlendsColors(t *testing.T) {
+	r := NewRenderer()
+	m := triangleMesh()
+	m.Colors = [][]int{{255, 0, 0}, {255, 0, 0}, {255, 0, 0}}
+	if err := r.DrawMesh(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Screen[YRES-1-200][250]; got[0] != 255 || got[1] != 0 || got[2] != 0 {
+		t.Errorf("middle of the triangle is %v, want [255 0 0]", got)
+	}
+}
+
+func TestMeshSkipsFacesPastTheVertices(t *testing.T) {
+	m := triangleMesh()
+	m.Colors = [][]int{{255, 0, 0}}
+	m.Faces = append(m.Faces, [3]int{0, 1, 7})
+
+	if n := columnCount(m.Triangles()); n != 3 {
+		t.Errorf("Triangles has %d corners, want 3", n)
+	}
+	welded := m.Weld(0)
+	if len(welded.Faces) != 1 || len(welded.Colors) != 0 {
+		t.Errorf("Weld kept %d faces and %d colors, want 1 and 0", len(welded.Faces), len(welded.Colors))
+	}
+	if report := m.Validate(); len(report.DegenerateFaces) != 1 {
+		t.Errorf("Validate found %d degenerate faces, want 1", len(report.DegenerateFaces))
+	}
+}
+
+func TestShadeTriangleShortColors(t *testing.T) {
+	screen := NewScreen(50, 50)
+	s := ScreenRasterizer{Screen: screen}
+	s.ShadeTriangle(0, 0, 0, 40, 0, 0, 0, 40, 0, []int{100, 200, 300}, []int{100}, []int{100, 200, 300})
+
+	if got := screen[49-5][5]; got[0] != 100 || got[1] != 0 || got[2] != 0 {
+		t.Errorf("pixel is %v, want [100 0 0]", got)
+	}
+}
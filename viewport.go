@@ -0,0 +1,71 @@
+// viewport provides rendering of a scene from several cameras into separate
+// regions of one screen, such as the quad view of CAD tools.
+package main
+
+// Viewport is a rectangular region of a screen. X and Y are the coordinates of
+// its lower left corner.
+type Viewport struct {
+	X, Y, Width, Height int
+}
+
+// View is a viewport and the camera matrix the scene is seen through in it.
+// The camera is applied about the center of the screen after the renderer's
+// own camera.
+type View struct {
+	Viewport Viewport
+	Camera   [][]float64
+}
+
+// QuadViews returns the views of a CAD-style quad view of a screen of size
+// width by height: top, front, side, and isometric, clockwise from the upper
+// left.
+func QuadViews(width, height int) []View {
+	w, h := width/2, height/2
+	return []View{
+		{Viewport{0, h, w, height - h}, MakeRotX(Degrees(90))},
+		{Viewport{w, h, width - w, height - h}, MakeProjectionMatrix(Orthographic)},
+		{Viewport{w, 0, width - w, h}, MakeRotY(Degrees(-90))},
+		{Viewport{0, 0, w, h}, MakeProjectionMatrix(Isometric)},
+	}
+}
+
+// RenderViews draws a scene into each view's region of the renderer's screen.
+// draw is called once per view with a renderer set up like r whose screen is
+// the size of the view's viewport and whose camera shrinks the whole screen
+// to fit it.
+func (r *Renderer) RenderViews(views []View, draw func(view *Renderer) error) error {
+	width, height := screenWidth(r.Screen), len(r.Screen)
+
+	for _, v := range views {
+		vp := v.Viewport
+		sub := r.forkSize(vp.Width, vp.Height)
+
+		camera := r.aboutScreenCenter(DeepCopy(v.Camera))
+		MultiplyMatrices(&camera, &sub.Camera)
+		shrink := MakeDilationMatrix(float64(vp.Width)/float64(width), float64(vp.Height)/float64(height), 1)
+		MultiplyMatrices(&shrink, &sub.Camera)
+
+		sub.Clear()
+		if err := draw(sub); err != nil {
+			return err
+		}
+		blit(r.Screen, sub.Output(), vp.X, vp.Y)
+	}
+	return nil
+}
+
+// blit copies src onto dst with src's lower left corner at (x, y) of dst.
+// Pixels falling outside dst are dropped.
+func blit(dst, src [][][]int, x, y int) {
+	for i, row := range src {
+		dstRow := len(dst) - (y + len(src) - i)
+		if dstRow < 0 || dstRow >= len(dst) {
+			continue
+		}
+		for j, rgb := range row {
+			if col := x + j; col >= 0 && col < len(dst[dstRow]) {
+				copy(dst[dstRow][col], rgb)
+			}
+		}
+	}
+}